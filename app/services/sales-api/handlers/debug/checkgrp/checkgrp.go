@@ -0,0 +1,135 @@
+// Package checkgrp maintains the group of handlers for health checking.
+package checkgrp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"os"
+	"runtime"
+	"sync/atomic"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// Check reports whether a dependency of the service is usable.
+type Check func(ctx context.Context) error
+
+// Drain reports the service as not ready once draining has started, so the
+// load balancer stops routing new requests before the servers shut down.
+type Drain struct {
+	draining int32
+}
+
+// Start marks the service as draining.
+func (d *Drain) Start() {
+	atomic.StoreInt32(&d.draining, 1)
+}
+
+// Check implements the Check function type.
+func (d *Drain) Check(ctx context.Context) error {
+	if atomic.LoadInt32(&d.draining) == 1 {
+		return errors.New("service is shutting down")
+	}
+	return nil
+}
+
+// Handlers manages the set of check endpoints.
+type Handlers struct {
+	Build  string
+	Log    *zap.SugaredLogger
+	Checks map[string]Check
+}
+
+// Readiness checks if the service dependencies are ready and if not will
+// return a 500 status. Do not respond by just returning an error because
+// further up in the call stack it will interpret that as a non-trusted error.
+func (h Handlers) Readiness(w http.ResponseWriter, r *http.Request) {
+	ctx, cancel := context.WithTimeout(r.Context(), time.Second)
+	defer cancel()
+
+	data := struct {
+		Status string            `json:"status"`
+		Checks map[string]string `json:"checks,omitempty"`
+	}{
+		Status: "ok",
+		Checks: make(map[string]string, len(h.Checks)),
+	}
+	statusCode := http.StatusOK
+
+	for name, check := range h.Checks {
+		if err := check(ctx); err != nil {
+			data.Status = "not ready"
+			data.Checks[name] = err.Error()
+			statusCode = http.StatusInternalServerError
+			continue
+		}
+		data.Checks[name] = "ok"
+	}
+
+	if err := response(w, statusCode, data); err != nil {
+		h.Log.Errorw("readiness", "ERROR", err)
+	}
+
+	h.Log.Infow("readiness", "statuscode", statusCode, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
+}
+
+// Liveness returns simple status info if the service is alive. If the
+// app is deployed to a Kubernetes cluster, it will also return pod, node, and
+// namespace details via the Downward API. The Kubernetes environment variables
+// need to be set within your Pod/Deployment manifest.
+func (h Handlers) Liveness(w http.ResponseWriter, r *http.Request) {
+	host, err := os.Hostname()
+	if err != nil {
+		host = "unavailable"
+	}
+
+	data := struct {
+		Status     string `json:"status,omitempty"`
+		Build      string `json:"build,omitempty"`
+		Host       string `json:"host,omitempty"`
+		Pod        string `json:"pod,omitempty"`
+		Node       string `json:"node,omitempty"`
+		Namespace  string `json:"namespace,omitempty"`
+		GOMAXPROCS int    `json:"GOMAXPROCS,omitempty"`
+	}{
+		Status:     "up",
+		Build:      h.Build,
+		Host:       host,
+		Pod:        os.Getenv("KUBERNETES_PODNAME"),
+		Node:       os.Getenv("KUBERNETES_NODENAME"),
+		Namespace:  os.Getenv("KUBERNETES_NAMESPACE"),
+		GOMAXPROCS: runtime.GOMAXPROCS(0),
+	}
+
+	statusCode := http.StatusOK
+	if err := response(w, statusCode, data); err != nil {
+		h.Log.Errorw("liveness", "ERROR", err)
+	}
+
+	h.Log.Infow("liveness", "statuscode", statusCode, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
+}
+
+func response(w http.ResponseWriter, statusCode int, data any) error {
+
+	// Convert the response value to JSON.
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		return err
+	}
+
+	// Set the content type and headers once we know marshaling has succeeded.
+	w.Header().Set("Content-Type", "application/json")
+
+	// Write the status code to the response.
+	w.WriteHeader(statusCode)
+
+	// Send the result back to the client.
+	if _, err := w.Write(jsonData); err != nil {
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,190 @@
+// Package loglevelgrp maintains the group of handlers for changing the log
+// level of the running service.
+package loglevelgrp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Handlers manages the log level endpoint. The level in place when the
+// handlers are constructed is the one changes revert to.
+type Handlers struct {
+	log        *zap.SugaredLogger
+	level      zap.AtomicLevel
+	base       zapcore.Level
+	defaultTTL time.Duration
+
+	mu       sync.Mutex
+	timer    *time.Timer
+	revertAt time.Time
+}
+
+// New constructs the handlers for the specified level. Changes made
+// without a ttl of their own revert after the default ttl, unless it is
+// zero.
+func New(log *zap.SugaredLogger, level zap.AtomicLevel, defaultTTL time.Duration) *Handlers {
+	return &Handlers{
+		log:        log,
+		level:      level,
+		base:       level.Level(),
+		defaultTTL: defaultTTL,
+	}
+}
+
+// status is the representation of the log level.
+type status struct {
+	Level    string     `json:"level"`
+	Base     string     `json:"base"`
+	RevertAt *time.Time `json:"revertAt,omitempty"`
+}
+
+// change is the request to change the log level. The ttl is a duration
+// such as "15m", where "0s" keeps the level until it is changed again.
+type change struct {
+	Level string  `json:"level"`
+	TTL   *string `json:"ttl"`
+}
+
+// LogLevel reports the log level on GET and changes it on PUT.
+func (h *Handlers) LogLevel(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		h.respond(w, r, http.StatusOK, h.status())
+
+	case http.MethodPut:
+		var c change
+		decoder := json.NewDecoder(r.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&c); err != nil {
+			h.respondError(w, r, fmt.Errorf("decoding request: %w", err))
+			return
+		}
+
+		level, err := zapcore.ParseLevel(c.Level)
+		if err != nil {
+			h.respondError(w, r, err)
+			return
+		}
+
+		ttl := h.defaultTTL
+		if c.TTL != nil {
+			if ttl, err = time.ParseDuration(*c.TTL); err != nil {
+				h.respondError(w, r, fmt.Errorf("parsing ttl: %w", err))
+				return
+			}
+		}
+
+		h.set(level, ttl)
+		h.respond(w, r, http.StatusOK, h.status())
+
+	default:
+		w.Header().Set("Allow", "GET, PUT")
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+// set changes the level, scheduling the reversion to the base level when
+// a ttl is provided.
+func (h *Handlers) set(level zapcore.Level, ttl time.Duration) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.timer != nil {
+		h.timer.Stop()
+		h.timer = nil
+	}
+	h.revertAt = time.Time{}
+
+	h.apply(level, "level changed", "ttl", ttl)
+
+	if level == h.base || ttl <= 0 {
+		return
+	}
+
+	h.revertAt = time.Now().Add(ttl).UTC()
+
+	var timer *time.Timer
+	timer = time.AfterFunc(ttl, func() {
+		h.mu.Lock()
+		defer h.mu.Unlock()
+
+		// A newer change replaced this one while we waited for the lock.
+		if h.timer != timer {
+			return
+		}
+
+		h.timer = nil
+		h.revertAt = time.Time{}
+		h.apply(h.base, "level reverted")
+	})
+	h.timer = timer
+}
+
+// apply sets the level and records the change. The record is written while
+// the more verbose of the two levels is in effect, so raising the level
+// doesn't suppress the record of raising it.
+func (h *Handlers) apply(level zapcore.Level, status string, keysAndValues ...any) {
+	keysAndValues = append([]any{"status", status, "from", h.level.Level(), "level", level}, keysAndValues...)
+
+	if level > h.level.Level() {
+		h.log.Warnw("loglevel", keysAndValues...)
+		h.level.SetLevel(level)
+		return
+	}
+
+	h.level.SetLevel(level)
+	h.log.Warnw("loglevel", keysAndValues...)
+}
+
+// status returns the current state of the log level.
+func (h *Handlers) status() status {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	s := status{
+		Level: h.level.Level().String(),
+		Base:  h.base.String(),
+	}
+	if !h.revertAt.IsZero() {
+		revertAt := h.revertAt
+		s.RevertAt = &revertAt
+	}
+
+	return s
+}
+
+// respondError reports a bad request back to the client.
+func (h *Handlers) respondError(w http.ResponseWriter, r *http.Request, err error) {
+	data := struct {
+		Error string `json:"error"`
+	}{
+		Error: err.Error(),
+	}
+	h.respond(w, r, http.StatusBadRequest, data)
+}
+
+// respond converts the value to JSON and sends it to the client.
+func (h *Handlers) respond(w http.ResponseWriter, r *http.Request, statusCode int, data any) {
+	jsonData, err := json.Marshal(data)
+	if err != nil {
+		h.log.Errorw("loglevel", "ERROR", err)
+		w.WriteHeader(http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(statusCode)
+
+	if _, err := w.Write(jsonData); err != nil {
+		h.log.Errorw("loglevel", "ERROR", err)
+	}
+
+	h.log.Infow("loglevel", "statuscode", statusCode, "method", r.Method, "path", r.URL.Path, "remoteaddr", r.RemoteAddr)
+}
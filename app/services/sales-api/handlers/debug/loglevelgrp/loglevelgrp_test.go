@@ -0,0 +1,184 @@
+package loglevelgrp_test
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/Joggz/services/app/services/sales-api/handlers/debug/loglevelgrp"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// syncBuffer is a buffer the reversion timer can log into while the test
+// reads it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (sb *syncBuffer) Write(p []byte) (int, error) {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.Write(p)
+}
+
+func (sb *syncBuffer) String() string {
+	sb.mu.Lock()
+	defer sb.mu.Unlock()
+	return sb.buf.String()
+}
+
+// status is the response of the log level endpoint.
+type status struct {
+	Level    string     `json:"level"`
+	Base     string     `json:"base"`
+	RevertAt *time.Time `json:"revertAt"`
+}
+
+// newHandlers constructs the handlers over a logger at the info level that
+// writes into the returned buffer.
+func newHandlers(defaultTTL time.Duration) (*loglevelgrp.Handlers, zap.AtomicLevel, *syncBuffer) {
+	var buf syncBuffer
+	level := zap.NewAtomicLevelAt(zapcore.InfoLevel)
+	core := zapcore.NewCore(zapcore.NewJSONEncoder(zap.NewProductionEncoderConfig()), zapcore.AddSync(&buf), level)
+
+	return loglevelgrp.New(zap.New(core).Sugar(), level, defaultTTL), level, &buf
+}
+
+// do sends the request to the handlers and decodes the response.
+func do(t *testing.T, h *loglevelgrp.Handlers, method string, body string) (int, status) {
+	r := httptest.NewRequest(method, "/debug/loglevel", strings.NewReader(body))
+	w := httptest.NewRecorder()
+	h.LogLevel(w, r)
+
+	var s status
+	if w.Code == http.StatusOK {
+		if err := json.NewDecoder(w.Body).Decode(&s); err != nil {
+			t.Fatalf("decoding response : %s", err)
+		}
+	}
+
+	return w.Code, s
+}
+
+// eventually reports if the level reaches the specified one in time.
+func eventually(level zap.AtomicLevel, want zapcore.Level) bool {
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		if level.Level() == want {
+			return true
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	return false
+}
+
+func TestLogLevel(t *testing.T) {
+	t.Log("Given the need to change the log level of the running service.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen raising the level without a ttl.", testID)
+		{
+			h, level, buf := newHandlers(0)
+
+			code, s := do(t, h, http.MethodPut, `{"level":"error"}`)
+			if code != http.StatusOK || s.Level != "error" || s.Base != "info" || s.RevertAt != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould change the level : got %d %+v", failed, testID, code, s)
+			}
+			t.Logf("\t%s\tTest %d:\tShould change the level.", success, testID)
+
+			if level.Level() != zapcore.ErrorLevel {
+				t.Fatalf("\t%s\tTest %d:\tShould set the level of the logger : got %s", failed, testID, level.Level())
+			}
+			t.Logf("\t%s\tTest %d:\tShould set the level of the logger.", success, testID)
+
+			if !strings.Contains(buf.String(), "level changed") {
+				t.Fatalf("\t%s\tTest %d:\tShould record the change : got %q", failed, testID, buf.String())
+			}
+			t.Logf("\t%s\tTest %d:\tShould record the change.", success, testID)
+
+			code, s = do(t, h, http.MethodGet, "")
+			if code != http.StatusOK || s.Level != "error" {
+				t.Fatalf("\t%s\tTest %d:\tShould report the level : got %d %+v", failed, testID, code, s)
+			}
+			t.Logf("\t%s\tTest %d:\tShould report the level.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen sending an invalid level.", testID)
+		{
+			h, level, _ := newHandlers(0)
+
+			if code, _ := do(t, h, http.MethodPut, `{"level":"loud"}`); code != http.StatusBadRequest {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a %d status code : got %d", failed, testID, http.StatusBadRequest, code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a %d status code.", success, testID, http.StatusBadRequest)
+
+			if level.Level() != zapcore.InfoLevel {
+				t.Fatalf("\t%s\tTest %d:\tShould keep the level : got %s", failed, testID, level.Level())
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep the level.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the ttl of a change expires.", testID)
+		{
+			h, level, buf := newHandlers(time.Hour)
+
+			code, s := do(t, h, http.MethodPut, `{"level":"debug","ttl":"20ms"}`)
+			if code != http.StatusOK || s.RevertAt == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould schedule the reversion : got %d %+v", failed, testID, code, s)
+			}
+			t.Logf("\t%s\tTest %d:\tShould schedule the reversion.", success, testID)
+
+			if !eventually(level, zapcore.InfoLevel) {
+				t.Fatalf("\t%s\tTest %d:\tShould revert to the base level : got %s", failed, testID, level.Level())
+			}
+			t.Logf("\t%s\tTest %d:\tShould revert to the base level.", success, testID)
+
+			code, s = do(t, h, http.MethodGet, "")
+			if code != http.StatusOK || s.RevertAt != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould no longer report a reversion : got %d %+v", failed, testID, code, s)
+			}
+			t.Logf("\t%s\tTest %d:\tShould no longer report a reversion.", success, testID)
+
+			if !strings.Contains(buf.String(), "level reverted") {
+				t.Fatalf("\t%s\tTest %d:\tShould record the reversion : got %q", failed, testID, buf.String())
+			}
+			t.Logf("\t%s\tTest %d:\tShould record the reversion.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen a second change replaces one with a ttl.", testID)
+		{
+			h, level, _ := newHandlers(time.Hour)
+
+			if code, _ := do(t, h, http.MethodPut, `{"level":"debug","ttl":"20ms"}`); code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould change the level : got %d", failed, testID, code)
+			}
+			if code, _ := do(t, h, http.MethodPut, `{"level":"warn","ttl":"0s"}`); code != http.StatusOK {
+				t.Fatalf("\t%s\tTest %d:\tShould change the level again : got %d", failed, testID, code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould change the level twice.", success, testID)
+
+			time.Sleep(100 * time.Millisecond)
+
+			if level.Level() != zapcore.WarnLevel {
+				t.Fatalf("\t%s\tTest %d:\tShould cancel the earlier reversion : got %s", failed, testID, level.Level())
+			}
+			t.Logf("\t%s\tTest %d:\tShould cancel the earlier reversion.", success, testID)
+		}
+	}
+}
@@ -6,13 +6,20 @@ import (
 	"net/http"
 	"net/http/pprof"
 	"os"
-	"encoding/json"
+	"time"
 
-	"github.com/dimfeld/httptreemux/v5"
+	"github.com/Joggz/services/app/services/sales-api/handlers/debug/checkgrp"
+	"github.com/Joggz/services/app/services/sales-api/handlers/debug/loglevelgrp"
+	v1 "github.com/Joggz/services/app/services/sales-api/handlers/v1"
+	"github.com/Joggz/services/app/services/sales-api/handlers/v1/docgrp"
+	"github.com/Joggz/services/business/sys/validate"
+	"github.com/Joggz/services/business/web/mid"
+	"github.com/Joggz/services/foundation/limiter"
+	"github.com/Joggz/services/foundation/openapi"
+	"github.com/Joggz/services/foundation/web"
 	"go.uber.org/zap"
 )
 
-
 // DebugStandardLibraryMux registers all the debug routes from the standard library
 // into a new mux bypassing the use of the DefaultServerMux. Using the
 // DefaultServerMux would be a security risk since a dependency could inject a
@@ -31,28 +38,142 @@ func DebugStandardLibraryMux() *http.ServeMux {
 	return mux
 }
 
+// DebugMuxConfig contains all the mandatory systems required by the debug
+// handlers.
+type DebugMuxConfig struct {
+	Build       string
+	Log         *zap.SugaredLogger
+	Checks      map[string]checkgrp.Check
+	LogLevel    zap.AtomicLevel
+	LogLevelTTL time.Duration
+}
+
+// DebugMux registers all the debug standard library routes and then custom
+// debug application routes for the service. This bypasses the use of the
+// DefaultServerMux. Using the DefaultServerMux would be a security risk since
+// a dependency could inject a handler into our service without us knowing it.
+func DebugMux(cfg DebugMuxConfig) http.Handler {
+	mux := DebugStandardLibraryMux()
+
+	// Register debug check endpoints.
+	cgh := checkgrp.Handlers{
+		Build:  cfg.Build,
+		Log:    cfg.Log,
+		Checks: cfg.Checks,
+	}
+	mux.HandleFunc("/debug/readiness", cgh.Readiness)
+	mux.HandleFunc("/debug/liveness", cgh.Liveness)
+
+	// Register the log level endpoint.
+	llh := loglevelgrp.New(cfg.Log, cfg.LogLevel, cfg.LogLevelTTL)
+	mux.HandleFunc("/debug/loglevel", llh.LogLevel)
+
+	return mux
+}
+
 // APIMuxConfig contains all the mandatory systems required by handlers.
 type APIMuxConfig struct {
+	Build    string
 	Shutdown chan os.Signal
 	Log      *zap.SugaredLogger
-	
-}
+	CORS     mid.CORSConfig
 
+	// Compress defines which responses are compressed. A MinSize of zero
+	// disables compression.
+	Compress mid.CompressConfig
+
+	// MaxBodySize is the default limit for request bodies of every route
+	// group. Routes can lower it with their own web.MaxBodySize option.
+	MaxBodySize int64
+
+	// Shed bounds the requests processed at once. A Limit of zero
+	// disables load shedding.
+	Shed limiter.Config
+
+	// Idempotency keeps the responses of unsafe requests made with an
+	// idempotency key. It is disabled when no Store is set.
+	Idempotency mid.IdempotencyConfig
+
+	// RateLimits holds the rate limit for each route group, keyed by
+	// the group name.
+	RateLimits map[string]mid.RateLimitConfig
+}
 
 // APIMux constructs a http.Handler with all application routes defined.
-func APIMux(cfg APIMuxConfig) *httptreemux.ContextMux  {
-	mux := httptreemux.NewContextMux()
-	h := func(w http.ResponseWriter, r *http.Request){
-		status := struct {
-			Status string;
-			Data string;
-		}{
-			Status: "OK",
-			Data: "My First Basic API response in Go",
-		}
-		json.NewEncoder(w).Encode(status)
-	}
-
-	mux.Handle(http.MethodGet, "/test", h)
-	return mux
-}
\ No newline at end of file
+func APIMux(cfg APIMuxConfig) *web.App {
+	mw := []web.Middleware{
+		mid.Logger(cfg.Log),
+	}
+	if cfg.Compress.MinSize > 0 {
+		mw = append(mw, mid.Compress(cfg.Log, cfg.Compress))
+	}
+	mw = append(mw,
+		mid.Errors(cfg.Log),
+		mid.Metrics(),
+	)
+	if cfg.Shed.Limit > 0 {
+		mw = append(mw, mid.Shed(cfg.Shed))
+	}
+	mw = append(mw, mid.Panics())
+
+	app := web.NewApp(cfg.Shutdown, cfg.Log, mw...)
+
+	// Accept cross origin requests only when origins have been configured.
+	if len(cfg.CORS.AllowedOrigins) > 0 {
+		app.EnableCORS(mid.Cors(cfg.CORS))
+	}
+
+	loadRoutes(app, cfg)
+
+	// Serve the description of every route loaded above.
+	dgh := docgrp.Handlers{
+		Doc: openAPI(cfg.Build, app.Endpoints()),
+	}
+	app.Handle(http.MethodGet, v1.Version, "/openapi.json", dgh.OpenAPI)
+
+	return app
+}
+
+// OpenAPI generates the OpenAPI document served by the api.
+func OpenAPI(cfg APIMuxConfig) openapi.Document {
+	app := web.NewApp(cfg.Shutdown, cfg.Log)
+	loadRoutes(app, cfg)
+
+	return openAPI(cfg.Build, app.Endpoints())
+}
+
+// loadRoutes binds the routes for the different versions of the API.
+func loadRoutes(app *web.App, cfg APIMuxConfig) {
+	app.HandleGroup(v1.Version, v1.Routes(v1.Config{
+		Log: cfg.Log,
+	}), groupMiddleware(v1.Version, cfg)...)
+}
+
+// groupMiddleware returns the middleware configured for the specified
+// route group.
+func groupMiddleware(group string, cfg APIMuxConfig) []web.Middleware {
+	var mw []web.Middleware
+
+	if cfg.MaxBodySize > 0 {
+		mw = append(mw, web.MaxBodySize(cfg.MaxBodySize))
+	}
+
+	if rl, exists := cfg.RateLimits[group]; exists && rl.Rate > 0 {
+		mw = append(mw, mid.RateLimit(rl))
+	}
+
+	if cfg.Idempotency.Store != nil {
+		mw = append(mw, mid.Idempotency(cfg.Idempotency))
+	}
+
+	return mw
+}
+
+// openAPI describes the specified endpoints.
+func openAPI(build string, endpoints []web.Endpoint) openapi.Document {
+	return openapi.Generate(openapi.Config{
+		Title:   "Sales API",
+		Version: build,
+		Error:   validate.ErrorResponse{},
+	}, endpoints)
+}
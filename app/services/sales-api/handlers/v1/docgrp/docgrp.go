@@ -0,0 +1,20 @@
+// Package docgrp maintains the group of handlers for api documentation.
+package docgrp
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/Joggz/services/foundation/openapi"
+	"github.com/Joggz/services/foundation/web"
+)
+
+// Handlers manages the set of documentation endpoints.
+type Handlers struct {
+	Doc openapi.Document
+}
+
+// OpenAPI returns the OpenAPI document describing the api.
+func (h Handlers) OpenAPI(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	return web.Respond(ctx, w, h.Doc, http.StatusOK)
+}
@@ -0,0 +1,55 @@
+// Package testgrp maintains the group of handlers for testing the api.
+package testgrp
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+
+	"github.com/Joggz/services/business/sys/validate"
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+// Handlers manages the set of test endpoints.
+type Handlers struct {
+	Log *zap.SugaredLogger
+}
+
+// Status is the response of the test endpoint.
+type Status struct {
+	Status string
+	Data   string
+}
+
+// NewTest is what the create endpoint requires from clients.
+type NewTest struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Validate checks the value against its validation tags.
+func (nt NewTest) Validate() error {
+	return validate.Check(nt)
+}
+
+// Test handler is for development.
+func (h Handlers) Test(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	status := Status{
+		Status: "OK",
+		Data:   "My First Basic API response in Go",
+	}
+
+	return web.Respond(ctx, w, status, http.StatusOK)
+}
+
+// Create handler is for development. It echoes back the value once it has
+// been decoded and validated.
+func (h Handlers) Create(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+	var nt NewTest
+	if err := web.Decode(r, &nt); err != nil {
+		return fmt.Errorf("unable to decode payload: %w", err)
+	}
+
+	return web.Respond(ctx, w, nt, http.StatusCreated)
+}
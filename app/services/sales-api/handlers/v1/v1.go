@@ -0,0 +1,50 @@
+// Package v1 contains the full set of handler functions and routes
+// supported by the v1 web api.
+package v1
+
+import (
+	"net/http"
+
+	"github.com/Joggz/services/app/services/sales-api/handlers/v1/testgrp"
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+// Version is the route group the v1 api is served under.
+const Version = "v1"
+
+// Config contains all the mandatory systems required by handlers.
+type Config struct {
+	Log *zap.SugaredLogger
+}
+
+// Routes returns all the routes supported by the v1 api. Later versions can
+// build on this set, overriding the routes that changed.
+func Routes(cfg Config) web.Routes {
+	tgh := testgrp.Handlers{
+		Log: cfg.Log,
+	}
+
+	return web.Routes{
+		{
+			Method:  http.MethodGet,
+			Path:    "/test",
+			Handler: tgh.Test,
+			Doc: web.Doc{
+				Summary:  "Development endpoint reporting the api is reachable.",
+				Response: testgrp.Status{},
+			},
+		},
+		{
+			Method:  http.MethodPost,
+			Path:    "/test",
+			Handler: tgh.Create,
+			Doc: web.Doc{
+				Summary:  "Development endpoint echoing back a validated value.",
+				Request:  testgrp.NewTest{},
+				Response: testgrp.NewTest{},
+				Status:   http.StatusCreated,
+			},
+		},
+	}
+}
@@ -2,32 +2,44 @@ package main
 
 import (
 	// "log"
-	"time"
-	"runtime"
-	"os"
+	"context"
+	"encoding/json"
 	"errors"
-	"fmt"
 	"expvar"
+	"fmt"
+	"net/http"
+	"os"
 	"os/signal"
+	"runtime"
 	"syscall"
-	"net/http"
-	"context"
-	
-	"go.uber.org/automaxprocs/maxprocs"	
+	"time"
+
 	"github.com/Joggz/services/app/services/sales-api/handlers"
+	"github.com/Joggz/services/app/services/sales-api/handlers/debug/checkgrp"
+	v1 "github.com/Joggz/services/app/services/sales-api/handlers/v1"
+	"github.com/Joggz/services/business/web/mid"
+	"github.com/Joggz/services/foundation/config"
+	"github.com/Joggz/services/foundation/handoff"
+	"github.com/Joggz/services/foundation/idempotency"
+	"github.com/Joggz/services/foundation/limiter"
+	"github.com/Joggz/services/foundation/tlsconfig"
+	"github.com/ardanlabs/conf"
+	"go.uber.org/automaxprocs/maxprocs"
 	"go.uber.org/zap"
 	"go.uber.org/zap/zapcore"
-	"github.com/ardanlabs/conf"
-	
 )
 
 var build = "develop"
 
 /*
-	- Need to figure out timeout for httpService
+- Need to figure out timeout for httpService
 */
-func  main()  {
-	log, err := initLogger("SALES_API")
+func main() {
+	// The level is shared with the debug mux so it can be changed while the
+	// service is running.
+	level := zap.NewAtomicLevel()
+
+	log, err := initLogger("SALES_API", level, "stdout")
 	if err != nil {
 		fmt.Println("Error constructing logger", err)
 		os.Exit(1)
@@ -35,45 +47,82 @@ func  main()  {
 
 	defer log.Sync()
 
-	if err := run(log); err != nil {
+	if err := run(log, level); err != nil {
 		log.Errorw("startup", "Error", err)
 		os.Exit(1)
 	}
 }
 
-func run(log *zap.SugaredLogger) error {
-
-		// =========================================================================
-	// GOMAXPROCS
-
-	// Want to see what maxprocs reports.
-	opt := maxprocs.Logger(log.Infof)
-
-	// Set the correct number of threads for the service
-	// based on what is available either by the machine or quotas.
-	if _, err := maxprocs.Set(opt); err != nil {
-		return fmt.Errorf("maxprocs: %w", err)
-		
-	}
-	log.Infow("startup", "GOMAXPROCS", runtime.GOMAXPROCS(0))
+func run(log *zap.SugaredLogger, level zap.AtomicLevel) error {
 
 	// =========================================================================
 	// Configuration
 
 	cfg := struct {
 		conf.Version
+		Args   conf.Args
+		Config struct {
+			File    string
+			Profile string
+			Dump    bool
+		}
+		Log struct {
+			Level    string        `conf:"default:info"`
+			LevelTTL time.Duration `conf:"default:15m"`
+		}
 		Web struct {
 			ReadTimeout     time.Duration `conf:"default:5s"`
 			WriteTimeout    time.Duration `conf:"default:10s"`
 			IdleTimeout     time.Duration `conf:"default:120s"`
 			ShutdownTimeout time.Duration `conf:"default:20s"`
+			DrainPeriod     time.Duration `conf:"default:0s"`
+			DebugShutdown   time.Duration `conf:"default:5s"`
+			UpgradeTimeout  time.Duration `conf:"default:30s"`
 			APIHost         string        `conf:"default:0.0.0.0:3000"`
 			DebugHost       string        `conf:"default:0.0.0.0:4000"`
+			MaxBodySize     int64         `conf:"default:1048576"`
+			CORS            struct {
+				AllowedOrigins   []string
+				AllowedMethods   []string      `conf:"default:GET;POST;PUT;PATCH;DELETE"`
+				AllowedHeaders   []string      `conf:"default:Accept;Authorization;Content-Type"`
+				MaxAge           time.Duration `conf:"default:10m"`
+				AllowCredentials bool          `conf:"default:false"`
+			}
+			Concurrency struct {
+				Limit         int           `conf:"default:200"`
+				QueueSize     int           `conf:"default:100"`
+				QueueTimeout  time.Duration `conf:"default:1s"`
+				Adaptive      bool          `conf:"default:false"`
+				MinLimit      int           `conf:"default:20"`
+				MaxLimit      int           `conf:"default:1000"`
+				TargetLatency time.Duration `conf:"default:250ms"`
+			}
+			Compression struct {
+				MinSize      int      `conf:"default:1024"`
+				ContentTypes []string `conf:"default:application/json;application/xml;text/*"`
+			}
+			TLS struct {
+				Enabled        bool   `conf:"default:false"`
+				CertFile       string `conf:"default:zarf/keys/tls.crt"`
+				KeyFile        string `conf:"default:zarf/keys/tls.key"`
+				ClientCAFile   string
+				MinVersion     string        `conf:"default:1.2"`
+				CipherPolicy   string        `conf:"default:default"`
+				ReloadInterval time.Duration `conf:"default:30s"`
+			}
+			Idempotency struct {
+				TTL time.Duration `conf:"default:24h"`
+			}
+			RateLimit struct {
+				Rate  float64 `conf:"default:50"`
+				Burst int     `conf:"default:100"`
+				Key   string  `conf:"default:ip"`
+			}
 		}
 	}{
 		Version: conf.Version{
-			SVN: build,
-			Desc:  "copyright information here",
+			SVN:  build,
+			Desc: "copyright information here",
 		},
 	}
 
@@ -87,6 +136,56 @@ func run(log *zap.SugaredLogger) error {
 		return fmt.Errorf("parsing config: %w", err)
 	}
 
+	// Parse again with the config file, now that we know where to find it,
+	// underneath the environment and flags.
+	settings, err := config.Parse(os.Args[1:], prefix, &cfg, config.Options{
+		File:    cfg.Config.File,
+		Profile: cfg.Config.Profile,
+	})
+	if err != nil {
+		return err
+	}
+
+	logLevel, err := zapcore.ParseLevel(cfg.Log.Level)
+	if err != nil {
+		return fmt.Errorf("parsing log level: %w", err)
+	}
+	level.SetLevel(logLevel)
+
+	// =========================================================================
+	// Commands
+
+	// Commands write their results to stdout, so they log to stderr to
+	// keep their output clean.
+	switch {
+	case cfg.Config.Dump:
+		fmt.Print(config.Dump(settings))
+		return nil
+
+	case cfg.Args.Num(0) == "openapi":
+		log, err := initLogger("SALES_API", level, "stderr")
+		if err != nil {
+			return fmt.Errorf("constructing command logger: %w", err)
+		}
+		defer log.Sync()
+
+		return writeOpenAPI(log, cfg.Args.Num(1))
+	}
+
+	// =========================================================================
+	// GOMAXPROCS
+
+	// Want to see what maxprocs reports.
+	opt := maxprocs.Logger(log.Infof)
+
+	// Set the correct number of threads for the service
+	// based on what is available either by the machine or quotas.
+	if _, err := maxprocs.Set(opt); err != nil {
+		return fmt.Errorf("maxprocs: %w", err)
+
+	}
+	log.Infow("startup", "GOMAXPROCS", runtime.GOMAXPROCS(0))
+
 	// =========================================================================
 	// App Starting
 
@@ -101,6 +200,27 @@ func run(log *zap.SugaredLogger) error {
 
 	expvar.NewString("build").Set(build)
 
+	// =========================================================================
+	// Listeners
+
+	// Listeners are inherited from a parent process during an upgrade or
+	// from systemd socket activation, otherwise they are opened here.
+	inherited, err := handoff.Inherited("api", "debug")
+	if err != nil {
+		return fmt.Errorf("inheriting listeners: %w", err)
+	}
+
+	apiLn, err := handoff.Listen(inherited, "api", cfg.Web.APIHost)
+	if err != nil {
+		return fmt.Errorf("api listener: %w", err)
+	}
+
+	debugLn, err := handoff.Listen(inherited, "debug", cfg.Web.DebugHost)
+	if err != nil {
+		return fmt.Errorf("debug listener: %w", err)
+	}
+
+	log.Infow("startup", "status", "listeners ready", "inherited", len(inherited))
 
 	// =========================================================================
 	// Start Debug Service
@@ -110,34 +230,101 @@ func run(log *zap.SugaredLogger) error {
 	// The Debug function returns a mux to listen and serve on for all the debug
 	// related endpoints. This includes the standard library endpoints.
 
-	// Construct the mux for the debug calls.
-	debugMux := handlers.DebugStandardLibraryMux()
+	// Construct the mux for the debug calls. The service has no external
+	// dependencies yet, so readiness only fails once shutdown has started.
+	var drain checkgrp.Drain
+	debugMux := handlers.DebugMux(handlers.DebugMuxConfig{
+		Build: build,
+		Log:   log,
+		Checks: map[string]checkgrp.Check{
+			"shutdown": drain.Check,
+		},
+		LogLevel:    level,
+		LogLevelTTL: cfg.Log.LevelTTL,
+	})
+
+	// Construct a server to service the debug requests against the mux.
+	debug := http.Server{
+		Addr:     cfg.Web.DebugHost,
+		Handler:  debugMux,
+		ErrorLog: zap.NewStdLog(log.Desugar()),
+	}
 
-	// Start the service listening for debug requests.
-	// Not concerned with shutting this down with load shedding.
+	// Start the service listening for debug requests. It is shut down after
+	// the api so it keeps reporting readiness while the api drains.
 	go func() {
-		if err := http.ListenAndServe(cfg.Web.DebugHost, debugMux); err != nil {
+		if err := debug.Serve(debugLn); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			log.Errorw("shutdown", "status", "debug v1 router closed", "host", cfg.Web.DebugHost, "ERROR", err)
 		}
 	}()
 
-
-// ========================================================================= 
+	// =========================================================================
 	// Make a channel to listen for an interrupt or terminate signal from the OS.
 	// Use a buffered channel because the signal package requires it.
 	shutdown := make(chan os.Signal, 1)
 	signal.Notify(shutdown, syscall.SIGINT, syscall.SIGTERM)
 	// <-shutdown
 
-	log.Infow("logging logging", "testing testing", "testing testing",)
-		// Construct the mux for the API calls.
-		apiMux := handlers.APIMux(handlers.APIMuxConfig{
-			Shutdown: shutdown,
-			Log:      log,
-			
-		})
+	// Make a channel to listen for requests to replace this process with
+	// a new binary without dropping connections.
+	upgrade := make(chan os.Signal, 1)
+	signal.Notify(upgrade, syscall.SIGUSR2)
+
+	log.Infow("logging logging", "testing testing", "testing testing")
+	// Clients are identified the same way for rate limiting and for
+	// scoping their idempotency keys.
+	rateLimitKey, err := mid.ParseKeyFunc(cfg.Web.RateLimit.Key)
+	if err != nil {
+		return fmt.Errorf("parsing rate limit key: %w", err)
+	}
+
+	// Cross origin requests are only accepted from the configured origins.
+	cors := mid.CORSConfig{
+		AllowedOrigins:   cfg.Web.CORS.AllowedOrigins,
+		AllowedMethods:   cfg.Web.CORS.AllowedMethods,
+		AllowedHeaders:   cfg.Web.CORS.AllowedHeaders,
+		MaxAge:           cfg.Web.CORS.MaxAge,
+		AllowCredentials: cfg.Web.CORS.AllowCredentials,
+	}
+	if err := cors.Validate(); err != nil {
+		return fmt.Errorf("validating cors config: %w", err)
+	}
 
-		// Construct a server to service the requests against the mux.
+	// Construct the mux for the API calls.
+	apiMux := handlers.APIMux(handlers.APIMuxConfig{
+		Build:       build,
+		Shutdown:    shutdown,
+		Log:         log,
+		MaxBodySize: cfg.Web.MaxBodySize,
+		Compress: mid.CompressConfig{
+			MinSize:      cfg.Web.Compression.MinSize,
+			ContentTypes: cfg.Web.Compression.ContentTypes,
+		},
+		CORS: cors,
+		Shed: limiter.Config{
+			Limit:         cfg.Web.Concurrency.Limit,
+			QueueSize:     cfg.Web.Concurrency.QueueSize,
+			QueueTimeout:  cfg.Web.Concurrency.QueueTimeout,
+			Adaptive:      cfg.Web.Concurrency.Adaptive,
+			MinLimit:      cfg.Web.Concurrency.MinLimit,
+			MaxLimit:      cfg.Web.Concurrency.MaxLimit,
+			TargetLatency: cfg.Web.Concurrency.TargetLatency,
+		},
+		Idempotency: mid.IdempotencyConfig{
+			Store:   idempotency.NewMemoryStore(),
+			TTL:     cfg.Web.Idempotency.TTL,
+			Subject: rateLimitKey,
+		},
+		RateLimits: map[string]mid.RateLimitConfig{
+			v1.Version: {
+				Rate:  cfg.Web.RateLimit.Rate,
+				Burst: cfg.Web.RateLimit.Burst,
+				Key:   rateLimitKey,
+			},
+		},
+	})
+
+	// Construct a server to service the requests against the mux.
 	api := http.Server{
 		Addr:         cfg.Web.APIHost,
 		Handler:      apiMux,
@@ -147,48 +334,189 @@ func run(log *zap.SugaredLogger) error {
 		ErrorLog:     zap.NewStdLog(log.Desugar()),
 	}
 
+	if cfg.Web.TLS.Enabled {
+
+		// Stop checking the certificate files for changes on shutdown.
+		tlsDone := make(chan struct{})
+		defer close(tlsDone)
+
+		tlsCfg, err := tlsconfig.New(tlsconfig.Config{
+			CertFile:       cfg.Web.TLS.CertFile,
+			KeyFile:        cfg.Web.TLS.KeyFile,
+			ClientCAFile:   cfg.Web.TLS.ClientCAFile,
+			MinVersion:     cfg.Web.TLS.MinVersion,
+			CipherPolicy:   cfg.Web.TLS.CipherPolicy,
+			ReloadInterval: cfg.Web.TLS.ReloadInterval,
+			OnReload: func(err error) {
+				if err != nil {
+					log.Errorw("tls", "status", "certificate reload failed", "ERROR", err)
+					return
+				}
+				log.Infow("tls", "status", "certificate reloaded", "certfile", cfg.Web.TLS.CertFile)
+			},
+			Done: tlsDone,
+		})
+		if err != nil {
+			return fmt.Errorf("configuring tls: %w", err)
+		}
+		api.TLSConfig = tlsCfg
+	}
+
 	// Make a channel to listen for errors coming from the listener. Use a
 	// buffered channel so the goroutine can exit if we don't collect this error.
 	serverErrors := make(chan error, 1)
 
 	// Start the service listening for api requests.
 	go func() {
-		log.Infow("startup", "status", "api router started", "host", api.Addr)
-		serverErrors <- api.ListenAndServe()
+		log.Infow("startup", "status", "api router started", "host", api.Addr, "tls", api.TLSConfig != nil)
+
+		// The certificate comes from the TLS config so no files are given.
+		if api.TLSConfig != nil {
+			serverErrors <- api.ServeTLS(apiLn, "", "")
+			return
+		}
+		serverErrors <- api.Serve(apiLn)
 	}()
 
+	// Let a parent process waiting on us know we are serving.
+	if err := handoff.Ready(); err != nil {
+		log.Errorw("startup", "status", "reporting ready to parent", "ERROR", err)
+	}
+
 	// =========================================================================
 	// Shutdown
 
-	// Blocking main and waiting for shutdown.
-	select {
-	case err := <-serverErrors:
-		return fmt.Errorf("server error: %w", err)
+	// Stop everything in order, each with its own deadline. Add any
+	// background workers to the end of the list.
+	steps := []shutdownStep{
+		{name: "api", timeout: cfg.Web.ShutdownTimeout, stop: api.Shutdown, kill: api.Close},
+		{name: "debug", timeout: cfg.Web.DebugShutdown, stop: debug.Shutdown, kill: debug.Close},
+	}
+
+	// Blocking main and waiting for shutdown or an upgrade.
+	for {
+		select {
+		case err := <-serverErrors:
+			return fmt.Errorf("server error: %w", err)
+
+		case sig := <-upgrade:
+			log.Infow("upgrade", "status", "upgrade started", "signal", sig)
+
+			child, err := handoff.Upgrade(cfg.Web.UpgradeTimeout,
+				handoff.Listener{Name: "api", Listener: apiLn},
+				handoff.Listener{Name: "debug", Listener: debugLn},
+			)
+			if err != nil {
+				log.Errorw("upgrade", "status", "upgrade failed, continuing to serve", "ERROR", err)
+				continue
+			}
+
+			// The new process is accepting connections on the same
+			// sockets so we only need to finish what we have.
+			log.Infow("upgrade", "status", "new process ready", "pid", child.Pid)
+			defer log.Infow("upgrade", "status", "upgrade complete", "pid", child.Pid)
+
+			if err := runShutdown(log, steps); err != nil {
+				return fmt.Errorf("could not stop gracefully: %w", err)
+			}
+			return nil
+
+		case sig := <-shutdown:
+			log.Infow("shutdown", "status", "shutdown started", "signal", sig)
+			defer log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
+
+			// Fail readiness and give the load balancer time to notice before
+			// we stop accepting requests. A second signal cuts this short.
+			drain.Start()
+			log.Infow("shutdown", "status", "draining", "period", cfg.Web.DrainPeriod)
 
-	case sig := <-shutdown:
-		log.Infow("shutdown", "status", "shutdown started", "signal", sig)
-		defer log.Infow("shutdown", "status", "shutdown complete", "signal", sig)
+			select {
+			case <-time.After(cfg.Web.DrainPeriod):
+			case sig := <-shutdown:
+				log.Infow("shutdown", "status", "draining cut short", "signal", sig)
+			}
+
+			if err := runShutdown(log, steps); err != nil {
+				return fmt.Errorf("could not stop gracefully: %w", err)
+			}
+			return nil
+		}
+	}
+}
 
-		// Give outstanding requests a deadline for completion.
-		ctx, cancel := context.WithTimeout(context.Background(), cfg.Web.ShutdownTimeout)
-		defer cancel()
+// shutdownStep describes how to stop one part of the service.
+type shutdownStep struct {
+	name    string
+	timeout time.Duration
+	stop    func(ctx context.Context) error
+	kill    func() error
+}
 
-		// Asking listener to shut down and shed load.
-		if err := api.Shutdown(ctx); err != nil {
-			api.Close()
-			return fmt.Errorf("could not stop server gracefully: %w", err)
+// runShutdown stops each step in order, forcing the ones that don't stop
+// within their deadline. Every step is run even when an earlier one fails
+// and the first error is returned.
+func runShutdown(log *zap.SugaredLogger, steps []shutdownStep) error {
+	var firstErr error
+
+	for _, step := range steps {
+		start := time.Now()
+		log.Infow("shutdown", "status", "stopping", "component", step.name, "timeout", step.timeout)
+
+		ctx, cancel := context.WithTimeout(context.Background(), step.timeout)
+		err := step.stop(ctx)
+		cancel()
+
+		if err != nil {
+			if step.kill != nil {
+				step.kill()
+			}
+			log.Errorw("shutdown", "status", "forced stop", "component", step.name, "since", time.Since(start), "ERROR", err)
+
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s: %w", step.name, err)
+			}
+			continue
 		}
+
+		log.Infow("shutdown", "status", "stopped", "component", step.name, "since", time.Since(start))
+	}
+
+	return firstErr
+}
+
+// writeOpenAPI writes the OpenAPI document describing the api to the
+// specified file, or to stdout when no file is provided.
+func writeOpenAPI(log *zap.SugaredLogger, path string) error {
+	doc := handlers.OpenAPI(handlers.APIMuxConfig{
+		Build: build,
+		Log:   log,
+	})
+
+	data, err := json.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return fmt.Errorf("marshaling openapi document: %w", err)
 	}
+	data = append(data, '\n')
 
+	if path == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
 
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		return fmt.Errorf("writing openapi document: %w", err)
+	}
+
+	log.Infow("openapi", "status", "document written", "path", path)
 	return nil
 }
 
-func initLogger(service string) (*zap.SugaredLogger, error) {
+func initLogger(service string, level zap.AtomicLevel, outputPath string) (*zap.SugaredLogger, error) {
 	// COnstruct application logger
 
 	config := zap.NewProductionConfig()
-	config.OutputPaths = []string{"stdout"}
+	config.Level = level
+	config.OutputPaths = []string{outputPath}
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
 	config.DisableStacktrace = true
 	config.InitialFields = map[string]any{
@@ -201,4 +529,4 @@ func initLogger(service string) (*zap.SugaredLogger, error) {
 	}
 
 	return log.Sugar(), nil
-}
\ No newline at end of file
+}
@@ -0,0 +1,95 @@
+// Package validate contains the support for validating models and reporting
+// errors back to the client in a consistent way.
+package validate
+
+import (
+	"encoding/json"
+	"errors"
+)
+
+// FieldError is used to indicate an error with a specific request field.
+type FieldError struct {
+	Field string `json:"field"`
+	Error string `json:"error"`
+}
+
+// FieldErrors represents a collection of field errors.
+type FieldErrors []FieldError
+
+// Error implements the error interface.
+func (fe FieldErrors) Error() string {
+	d, err := json.Marshal(fe)
+	if err != nil {
+		return err.Error()
+	}
+	return string(d)
+}
+
+// Fields returns the fields that failed validation.
+func (fe FieldErrors) Fields() map[string]string {
+	m := make(map[string]string, len(fe))
+	for _, fld := range fe {
+		m[fld.Field] = fld.Error
+	}
+	return m
+}
+
+// IsFieldErrors checks if an error of type FieldErrors exists.
+func IsFieldErrors(err error) bool {
+	var fe FieldErrors
+	return errors.As(err, &fe)
+}
+
+// GetFieldErrors returns the FieldErrors contained in the error.
+func GetFieldErrors(err error) FieldErrors {
+	var fe FieldErrors
+	if !errors.As(err, &fe) {
+		return nil
+	}
+	return fe
+}
+
+// ErrorResponse is the form used for API responses from failures in the API.
+type ErrorResponse struct {
+	Error  string            `json:"error"`
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// RequestError is used to pass an error during the request through the
+// application with web specific context.
+type RequestError struct {
+	Err    error
+	Status int
+}
+
+// NewRequestError wraps a provided error with an HTTP status code. This
+// function should be used when handlers encounter expected errors.
+func NewRequestError(err error, status int) error {
+	return &RequestError{err, status}
+}
+
+// Error implements the error interface. It uses the default message of the
+// wrapped error. This is what will be shown in the services' logs.
+func (re *RequestError) Error() string {
+	return re.Err.Error()
+}
+
+// Unwrap returns the wrapped error.
+func (re *RequestError) Unwrap() error {
+	return re.Err
+}
+
+// IsRequestError checks if an error of type RequestError exists.
+func IsRequestError(err error) bool {
+	var re *RequestError
+	return errors.As(err, &re)
+}
+
+// GetRequestError returns a copy of the RequestError pointer.
+func GetRequestError(err error) *RequestError {
+	var re *RequestError
+	if !errors.As(err, &re) {
+		return nil
+	}
+	return re
+}
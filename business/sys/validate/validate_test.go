@@ -0,0 +1,66 @@
+package validate_test
+
+import (
+	"testing"
+
+	"github.com/Joggz/services/business/sys/validate"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// newUser is a value validated by its struct tags.
+type newUser struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+func TestCheck(t *testing.T) {
+	tt := []struct {
+		name   string
+		val    newUser
+		fields []string
+	}{
+		{"valid", newUser{Name: "bill", Email: "bill@example.com"}, nil},
+		{"missing name", newUser{Email: "bill@example.com"}, []string{"name"}},
+		{"bad email", newUser{Name: "bill", Email: "bill"}, []string{"email"}},
+		{"empty", newUser{}, []string{"name", "email"}},
+	}
+
+	t.Log("Given the need to validate values against their struct tags.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen checking a %s value.", testID, test.name)
+			{
+				err := validate.Check(test.val)
+
+				if test.fields == nil {
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould pass validation : %s", failed, testID, err)
+					}
+					t.Logf("\t%s\tTest %d:\tShould pass validation.", success, testID)
+					continue
+				}
+
+				if !validate.IsFieldErrors(err) {
+					t.Fatalf("\t%s\tTest %d:\tShould report field errors : got %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould report field errors.", success, testID)
+
+				fields := validate.GetFieldErrors(err).Fields()
+				if len(fields) != len(test.fields) {
+					t.Fatalf("\t%s\tTest %d:\tShould report %d fields : got %v", failed, testID, len(test.fields), fields)
+				}
+				for _, name := range test.fields {
+					if fields[name] == "" {
+						t.Fatalf("\t%s\tTest %d:\tShould report the %s field by its json name : got %v", failed, testID, name, fields)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould report the fields by their json names.", success, testID)
+			}
+		}
+	}
+}
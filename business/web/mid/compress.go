@@ -0,0 +1,283 @@
+package mid
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/Joggz/services/foundation/web"
+	"github.com/andybalholm/brotli"
+	"go.uber.org/zap"
+)
+
+// CompressConfig defines which responses are compressed.
+type CompressConfig struct {
+
+	// MinSize is the smallest response body, in bytes, worth compressing.
+	MinSize int
+
+	// ContentTypes lists the media types that are compressed. A type ending
+	// in "/*" matches every subtype.
+	ContentTypes []string
+}
+
+// Pools of compressors so they are reused across requests. Constructing
+// them allocates large internal buffers.
+var (
+	gzipPool = sync.Pool{
+		New: func() any {
+			return gzip.NewWriter(io.Discard)
+		},
+	}
+	brotliPool = sync.Pool{
+		New: func() any {
+			return brotli.NewWriter(io.Discard)
+		},
+	}
+)
+
+// Compress compresses response bodies with brotli or gzip based on what the
+// client accepts through the Accept-Encoding header.
+func Compress(log *zap.SugaredLogger, cfg CompressConfig) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			w.Header().Add("Vary", "Accept-Encoding")
+
+			encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"))
+			if encoding == "" {
+				return handler(ctx, w, r)
+			}
+
+			cw := compressWriter{
+				ResponseWriter: w,
+				cfg:            cfg,
+				encoding:       encoding,
+			}
+
+			// Call the next handler writing through the compressor.
+			err := handler(ctx, &cw, r)
+
+			// Write out what is still buffered or held by the compressor,
+			// even on error, since a response may already be in flight.
+			// Failing to do so means the client went away, which is only
+			// worth logging.
+			if err := cw.close(); err != nil {
+				log.Errorw("compress", "traceid", web.GetTraceID(ctx), "ERROR", err)
+			}
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// negotiateEncoding picks the encoding to use from the Accept-Encoding
+// header, preferring brotli when the client gives both the same weight.
+func negotiateEncoding(accept string) string {
+	var best string
+	var bestQ float64
+
+	for _, part := range strings.Split(accept, ",") {
+		params := strings.Split(part, ";")
+		coding := strings.ToLower(strings.TrimSpace(params[0]))
+
+		q := 1.0
+		for _, param := range params[1:] {
+			name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+			if ok && strings.TrimSpace(name) == "q" {
+				f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+				if err != nil {
+					f = 0
+				}
+				q = f
+			}
+		}
+
+		switch coding {
+		case "br", "gzip":
+		case "*":
+			coding = "br"
+		default:
+			continue
+		}
+
+		if q > bestQ || (q == bestQ && q > 0 && coding == "br") {
+			best = coding
+			bestQ = q
+		}
+	}
+
+	return best
+}
+
+// =============================================================================
+
+// compressWriter buffers the start of the response until it knows whether
+// the response is worth compressing.
+type compressWriter struct {
+	http.ResponseWriter
+	cfg      CompressConfig
+	encoding string
+
+	statusCode  int
+	buf         []byte
+	decided     bool
+	compressor  io.WriteCloser
+	wroteHeader bool
+}
+
+// WriteHeader holds on to the status code until the body is inspected.
+func (cw *compressWriter) WriteHeader(statusCode int) {
+	if cw.statusCode == 0 {
+		cw.statusCode = statusCode
+	}
+}
+
+// Write buffers the response until MinSize is reached and then writes
+// through the compressor or directly to the client.
+func (cw *compressWriter) Write(p []byte) (int, error) {
+	if cw.decided {
+		if cw.compressor != nil {
+			return cw.compressor.Write(p)
+		}
+		return cw.ResponseWriter.Write(p)
+	}
+
+	cw.buf = append(cw.buf, p...)
+	if len(cw.buf) < cw.cfg.MinSize {
+		return len(p), nil
+	}
+
+	if err := cw.decide(true); err != nil {
+		return 0, err
+	}
+
+	return len(p), nil
+}
+
+// Unwrap returns the original response writer.
+func (cw *compressWriter) Unwrap() http.ResponseWriter {
+	return cw.ResponseWriter
+}
+
+// decide sends the headers and the buffered bytes, compressing them if the
+// response is large enough and of a compressible type.
+func (cw *compressWriter) decide(largeEnough bool) error {
+	cw.decided = true
+
+	h := cw.Header()
+	if h.Get("Content-Encoding") == "" && cw.compressible(h.Get("Content-Type")) {
+
+		// The entity tag is marked whether or not there is a body to
+		// compress, so a 304 carries the same validator the 200 would.
+		if etag := h.Get("ETag"); etag != "" {
+			h.Set("ETag", web.EncodedETag(etag, cw.encoding))
+		}
+
+		if largeEnough {
+			h.Set("Content-Encoding", cw.encoding)
+			h.Del("Content-Length")
+
+			switch cw.encoding {
+			case "br":
+				bw := brotliPool.Get().(*brotli.Writer)
+				bw.Reset(cw.ResponseWriter)
+				cw.compressor = bw
+			default:
+				gw := gzipPool.Get().(*gzip.Writer)
+				gw.Reset(cw.ResponseWriter)
+				cw.compressor = gw
+			}
+		}
+	}
+
+	cw.writeHeader()
+
+	buf := cw.buf
+	cw.buf = nil
+	if len(buf) == 0 {
+		return nil
+	}
+
+	if cw.compressor != nil {
+		_, err := cw.compressor.Write(buf)
+		return err
+	}
+
+	_, err := cw.ResponseWriter.Write(buf)
+	return err
+}
+
+// writeHeader sends the status code held by the writer, if any.
+func (cw *compressWriter) writeHeader() {
+	if cw.wroteHeader {
+		return
+	}
+	cw.wroteHeader = true
+
+	if cw.statusCode != 0 {
+		cw.ResponseWriter.WriteHeader(cw.statusCode)
+	}
+}
+
+// close completes the response and returns the compressor to its pool.
+func (cw *compressWriter) close() error {
+	if !cw.decided {
+		if cw.statusCode == 0 && len(cw.buf) == 0 {
+			return nil
+		}
+		return cw.decide(false)
+	}
+
+	if cw.compressor == nil {
+		return nil
+	}
+
+	err := cw.compressor.Close()
+
+	switch c := cw.compressor.(type) {
+	case *brotli.Writer:
+		c.Reset(io.Discard)
+		brotliPool.Put(c)
+	case *gzip.Writer:
+		c.Reset(io.Discard)
+		gzipPool.Put(c)
+	}
+	cw.compressor = nil
+
+	return err
+}
+
+// compressible reports if the content type is one we compress.
+func (cw *compressWriter) compressible(contentType string) bool {
+	mediaType := strings.ToLower(strings.TrimSpace(strings.Split(contentType, ";")[0]))
+	if mediaType == "" {
+		return false
+	}
+
+	for _, ct := range cw.cfg.ContentTypes {
+		ct = strings.ToLower(ct)
+		if strings.HasSuffix(ct, "/*") {
+			if strings.HasPrefix(mediaType, strings.TrimSuffix(ct, "*")) {
+				return true
+			}
+			continue
+		}
+		if ct == mediaType {
+			return true
+		}
+	}
+
+	return false
+}
@@ -0,0 +1,159 @@
+package mid_test
+
+import (
+	"compress/gzip"
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/Joggz/services/business/web/mid"
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+func TestCompress(t *testing.T) {
+	cfg := mid.CompressConfig{
+		MinSize:      64,
+		ContentTypes: []string{"application/json"},
+	}
+
+	data := struct {
+		Data string `json:"data"`
+	}{
+		Data: strings.Repeat("sales ", 100),
+	}
+
+	t.Log("Given the need to compress large responses.")
+	{
+		for testID, gone := range []bool{false, true} {
+			when := "the client reads the response"
+			if gone {
+				when = "the client has gone away"
+			}
+
+			t.Logf("\tTest %d:\tWhen %s.", testID, when)
+			{
+				log := zap.NewNop().Sugar()
+				shutdown := make(chan os.Signal, 1)
+				app := web.NewApp(shutdown, log, mid.Compress(log, cfg), mid.Errors(log))
+
+				handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return web.Respond(ctx, w, data, http.StatusOK)
+				}
+				app.Handle(http.MethodGet, "", "/test", handler)
+
+				r := httptest.NewRequest(http.MethodGet, "/test", nil)
+				r.Header.Set("Accept-Encoding", "gzip")
+
+				rec := httptest.NewRecorder()
+				var w http.ResponseWriter = rec
+				if gone {
+					w = failingWriter{rec}
+				}
+				app.ServeHTTP(w, r)
+
+				select {
+				case <-shutdown:
+					t.Fatalf("\t%s\tTest %d:\tShould not signal shutdown.", failed, testID)
+				default:
+				}
+				t.Logf("\t%s\tTest %d:\tShould not signal shutdown.", success, testID)
+
+				if gone {
+					continue
+				}
+
+				if ce := rec.Header().Get("Content-Encoding"); ce != "gzip" {
+					t.Fatalf("\t%s\tTest %d:\tShould be gzip encoded : got %q", failed, testID, ce)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be gzip encoded.", success, testID)
+
+				etag := rec.Header().Get("ETag")
+				if !strings.HasSuffix(etag, `-gzip"`) {
+					t.Fatalf("\t%s\tTest %d:\tShould mark the entity tag with the encoding : got %q", failed, testID, etag)
+				}
+				t.Logf("\t%s\tTest %d:\tShould mark the entity tag with the encoding.", success, testID)
+
+				gr, err := gzip.NewReader(rec.Body)
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to read the body : %s", failed, testID, err)
+				}
+				body, err := io.ReadAll(gr)
+				if err != nil || !strings.Contains(string(body), data.Data) {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to decompress the body : %v", failed, testID, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be able to decompress the body.", success, testID)
+			}
+		}
+	}
+}
+
+func TestCompressRevalidate(t *testing.T) {
+	cfg := mid.CompressConfig{
+		MinSize:      64,
+		ContentTypes: []string{"application/json"},
+	}
+
+	data := struct {
+		Data string `json:"data"`
+	}{
+		Data: strings.Repeat("sales ", 100),
+	}
+
+	t.Log("Given the need to revalidate compressed responses.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen revalidating the entity tag of a gzip response.", testID)
+		{
+			log := zap.NewNop().Sugar()
+			app := web.NewApp(make(chan os.Signal, 1), log, mid.Compress(log, cfg), mid.Errors(log))
+
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.Respond(ctx, w, data, http.StatusOK)
+			}
+			app.Handle(http.MethodGet, "", "/test", handler)
+			app.Handle(http.MethodHead, "", "/test", handler)
+
+			r := httptest.NewRequest(http.MethodGet, "/test", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			etag := w.Header().Get("ETag")
+			if w.Code != http.StatusOK || !strings.HasSuffix(etag, `-gzip"`) {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a marked entity tag : got %d %q", failed, testID, w.Code, etag)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a marked entity tag.", success, testID)
+
+			r = httptest.NewRequest(http.MethodGet, "/test", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			r.Header.Set("If-None-Match", etag)
+			w = httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if w.Code != http.StatusNotModified {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a %d status code : got %d", failed, testID, http.StatusNotModified, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a %d status code.", success, testID, http.StatusNotModified)
+
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Fatalf("\t%s\tTest %d:\tShould echo the same entity tag %q : got %q", failed, testID, etag, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould echo the same entity tag.", success, testID)
+
+			r = httptest.NewRequest(http.MethodHead, "/test", nil)
+			r.Header.Set("Accept-Encoding", "gzip")
+			w = httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if got := w.Header().Get("ETag"); got != etag {
+				t.Fatalf("\t%s\tTest %d:\tShould send the same entity tag for HEAD %q : got %q", failed, testID, etag, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould send the same entity tag for HEAD.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,111 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Joggz/services/foundation/web"
+)
+
+// CORSConfig defines which cross origin requests are allowed.
+type CORSConfig struct {
+	AllowedOrigins   []string
+	AllowedMethods   []string
+	AllowedHeaders   []string
+	MaxAge           time.Duration
+	AllowCredentials bool
+}
+
+// Validate checks the configuration is safe to use. Allowing credentials
+// from any origin would let every site make authenticated requests on
+// behalf of the user, so the wildcard origin can't be combined with them.
+func (cfg CORSConfig) Validate() error {
+	if !cfg.AllowCredentials {
+		return nil
+	}
+
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			return errors.New("the wildcard origin can't be used when credentials are allowed")
+		}
+	}
+
+	return nil
+}
+
+// Cors sets the response headers needed for Cross-Origin Resource Sharing
+// and answers preflight requests without calling the route handler. It
+// panics if the configuration doesn't pass Validate.
+func Cors(cfg CORSConfig) web.Middleware {
+	if err := cfg.Validate(); err != nil {
+		panic("cors: " + err.Error())
+	}
+
+	allowAll := false
+	origins := make(map[string]bool, len(cfg.AllowedOrigins))
+	for _, origin := range cfg.AllowedOrigins {
+		if origin == "*" {
+			allowAll = true
+			continue
+		}
+		origins[strings.ToLower(origin)] = true
+	}
+
+	methods := strings.Join(cfg.AllowedMethods, ", ")
+	headers := strings.Join(cfg.AllowedHeaders, ", ")
+	maxAge := strconv.Itoa(int(cfg.MaxAge.Seconds()))
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// Responses differ based on the origin so caches must know.
+			w.Header().Add("Vary", "Origin")
+
+			// Requests without an origin or from an origin we don't
+			// recognize are processed without any CORS headers, leaving
+			// the browser to block the response.
+			origin := r.Header.Get("Origin")
+			if origin == "" || (!allowAll && !origins[strings.ToLower(origin)]) {
+				return handler(ctx, w, r)
+			}
+
+			// A wildcard can't be used when credentials are allowed so the
+			// origin is always echoed back.
+			w.Header().Set("Access-Control-Allow-Origin", origin)
+			if cfg.AllowCredentials {
+				w.Header().Set("Access-Control-Allow-Credentials", "true")
+			}
+			w.Header().Set("Access-Control-Expose-Headers", web.TraceIDHeader)
+
+			// Anything other than a preflight request continues on.
+			if r.Method != http.MethodOptions || r.Header.Get("Access-Control-Request-Method") == "" {
+				return handler(ctx, w, r)
+			}
+
+			w.Header().Add("Vary", "Access-Control-Request-Method")
+			w.Header().Add("Vary", "Access-Control-Request-Headers")
+			if methods != "" {
+				w.Header().Set("Access-Control-Allow-Methods", methods)
+			}
+			if headers != "" {
+				w.Header().Set("Access-Control-Allow-Headers", headers)
+			}
+			if cfg.MaxAge > 0 {
+				w.Header().Set("Access-Control-Max-Age", maxAge)
+			}
+
+			return web.Respond(ctx, w, nil, http.StatusNoContent)
+		}
+
+		return h
+	}
+
+	return m
+}
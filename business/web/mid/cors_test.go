@@ -0,0 +1,81 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Joggz/services/business/web/mid"
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+func TestCORSValidate(t *testing.T) {
+	tt := []struct {
+		name  string
+		cfg   mid.CORSConfig
+		valid bool
+	}{
+		{"wildcard", mid.CORSConfig{AllowedOrigins: []string{"*"}}, true},
+		{"origin with credentials", mid.CORSConfig{AllowedOrigins: []string{"https://sales.example.com"}, AllowCredentials: true}, true},
+		{"wildcard with credentials", mid.CORSConfig{AllowedOrigins: []string{"https://sales.example.com", "*"}, AllowCredentials: true}, false},
+	}
+
+	t.Log("Given the need to reject unsafe CORS configurations.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen validating a %s configuration.", testID, test.name)
+			{
+				err := test.cfg.Validate()
+				if (err == nil) != test.valid {
+					t.Fatalf("\t%s\tTest %d:\tShould be valid %v : got %v", failed, testID, test.valid, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be valid %v.", success, testID, test.valid)
+			}
+		}
+	}
+}
+
+func TestCORSPreflight(t *testing.T) {
+	t.Log("Given the need to answer preflight requests.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen no headers are allowed.", testID)
+		{
+			log := zap.NewNop().Sugar()
+			app := web.NewApp(make(chan os.Signal, 1), log, mid.Errors(log))
+			app.EnableCORS(mid.Cors(mid.CORSConfig{
+				AllowedOrigins: []string{"https://sales.example.com"},
+				AllowedMethods: []string{http.MethodGet},
+			}))
+
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return web.Respond(ctx, w, nil, http.StatusNoContent)
+			}
+			app.Handle(http.MethodGet, "", "/test", handler)
+
+			r := httptest.NewRequest(http.MethodOptions, "/test", nil)
+			r.Header.Set("Origin", "https://sales.example.com")
+			r.Header.Set("Access-Control-Request-Method", http.MethodGet)
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if w.Code != http.StatusNoContent {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a %d status code : got %d", failed, testID, http.StatusNoContent, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a %d status code.", success, testID, http.StatusNoContent)
+
+			if got := w.Header().Get("Access-Control-Allow-Methods"); got != http.MethodGet {
+				t.Fatalf("\t%s\tTest %d:\tShould allow the GET method : got %q", failed, testID, got)
+			}
+			t.Logf("\t%s\tTest %d:\tShould allow the GET method.", success, testID)
+
+			if _, exists := w.Header()["Access-Control-Allow-Headers"]; exists {
+				t.Fatalf("\t%s\tTest %d:\tShould not send the allowed headers.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not send the allowed headers.", success, testID)
+		}
+	}
+}
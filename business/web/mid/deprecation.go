@@ -0,0 +1,40 @@
+package mid
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/Joggz/services/foundation/web"
+)
+
+// Deprecation marks the responses of a retired api version with the
+// Deprecation (RFC 9745) and Sunset (RFC 8594) headers. The successor is the
+// path of the version clients should move to and is optional.
+func Deprecation(deprecated time.Time, sunset time.Time, successor string) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// Headers must be set before the handler writes the response.
+			w.Header().Set("Deprecation", fmt.Sprintf("@%d", deprecated.Unix()))
+			if !sunset.IsZero() {
+				w.Header().Set("Sunset", sunset.UTC().Format(http.TimeFormat))
+			}
+			if successor != "" {
+				w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", successor))
+			}
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
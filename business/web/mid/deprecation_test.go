@@ -0,0 +1,74 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Joggz/services/business/web/mid"
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+func TestDeprecation(t *testing.T) {
+	deprecated := time.Date(2022, time.June, 1, 0, 0, 0, 0, time.UTC)
+	sunset := time.Date(2022, time.December, 1, 0, 0, 0, 0, time.UTC)
+
+	tt := []struct {
+		name      string
+		sunset    time.Time
+		successor string
+		want      map[string]string
+	}{
+		{
+			name:      "a sunset and successor",
+			sunset:    sunset,
+			successor: "/v2",
+			want: map[string]string{
+				"Deprecation": "@1654041600",
+				"Sunset":      "Thu, 01 Dec 2022 00:00:00 GMT",
+				"Link":        `</v2>; rel="successor-version"`,
+			},
+		},
+		{
+			name: "no sunset or successor",
+			want: map[string]string{
+				"Deprecation": "@1654041600",
+				"Sunset":      "",
+				"Link":        "",
+			},
+		},
+	}
+
+	t.Log("Given the need to tell clients a version is retired.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen deprecating with %s.", testID, test.name)
+			{
+				app := web.NewApp(make(chan os.Signal, 1), zap.NewNop().Sugar())
+
+				handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return web.Respond(ctx, w, nil, http.StatusNoContent)
+				}
+				routes := web.Routes{
+					{Method: http.MethodGet, Path: "/test", Handler: handler},
+				}
+				app.HandleGroup("v1", routes, mid.Deprecation(deprecated, test.sunset, test.successor))
+
+				r := httptest.NewRequest(http.MethodGet, "/v1/test", nil)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				for k, v := range test.want {
+					if got := w.Header().Get(k); got != v {
+						t.Fatalf("\t%s\tTest %d:\tShould set the %s header to %q : got %q", failed, testID, k, v, got)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould set the deprecation headers.", success, testID)
+			}
+		}
+	}
+}
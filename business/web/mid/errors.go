@@ -0,0 +1,115 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/Joggz/services/business/sys/validate"
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+// Errors handles errors coming out of the call chain. It detects normal
+// application errors which are used to respond to the client in a uniform way.
+// Unexpected errors (status >= 500) are logged at error level and their
+// details are kept from the client.
+func Errors(log *zap.SugaredLogger) web.Middleware {
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+
+			// Run the next handler and catch any propagated error.
+			if err := handler(ctx, w, r); err != nil {
+
+				// Build out the error response.
+				var er validate.ErrorResponse
+				var status int
+				switch {
+				case validate.IsFieldErrors(err):
+					fieldErrors := validate.GetFieldErrors(err)
+					er = validate.ErrorResponse{
+						Error:  "data validation error",
+						Fields: fieldErrors.Fields(),
+					}
+					status = http.StatusBadRequest
+
+				case errors.Is(err, web.ErrBodyTooLarge):
+					er = validate.ErrorResponse{
+						Error: web.ErrBodyTooLarge.Error(),
+					}
+					status = http.StatusRequestEntityTooLarge
+
+				case errors.Is(err, web.ErrMalformedBody):
+					er = validate.ErrorResponse{
+						Error: err.Error(),
+					}
+					status = http.StatusBadRequest
+
+				case errors.Is(err, web.ErrDeadlineExceeded):
+					er = validate.ErrorResponse{
+						Error: web.ErrDeadlineExceeded.Error(),
+					}
+					status = http.StatusGatewayTimeout
+
+				case errors.Is(err, web.ErrPreconditionFailed):
+					er = validate.ErrorResponse{
+						Error: web.ErrPreconditionFailed.Error(),
+					}
+					status = http.StatusPreconditionFailed
+
+				case validate.IsRequestError(err):
+					reqErr := validate.GetRequestError(err)
+					er = validate.ErrorResponse{
+						Error: reqErr.Error(),
+					}
+					status = reqErr.Status
+
+					// Server errors can carry internal details, like the
+					// text of a database driver error, the client shouldn't see.
+					if status >= http.StatusInternalServerError {
+						er.Error = http.StatusText(status)
+					}
+
+				default:
+					er = validate.ErrorResponse{
+						Error: http.StatusText(http.StatusInternalServerError),
+					}
+					status = http.StatusInternalServerError
+				}
+
+				// Log the error. Client errors are expected as part of normal
+				// operation, so only server errors are logged at error level.
+				if status >= http.StatusInternalServerError {
+					log.Errorw("ERROR", "traceid", web.GetTraceID(ctx), "ERROR", err)
+				} else {
+					log.Infow("request error", "traceid", web.GetTraceID(ctx), "status", status, "ERROR", err)
+				}
+
+				// Respond with the error back to the client. Failing to do so
+				// usually means the client went away, which is no reason to
+				// shut down the service.
+				if err := web.Respond(ctx, w, er, status); err != nil {
+					log.Errorw("ERROR", "traceid", web.GetTraceID(ctx), "ERROR", fmt.Errorf("responding with error: %w", err))
+				}
+
+				// If we receive the shutdown err we need to return it
+				// back to the base handler to shut down the service.
+				if web.IsShutdown(err) {
+					return err
+				}
+			}
+
+			// The error has been handled so we can stop propagating it.
+			return nil
+		}
+
+		return h
+	}
+
+	return m
+}
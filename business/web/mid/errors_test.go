@@ -0,0 +1,213 @@
+package mid_test
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Joggz/services/business/sys/validate"
+	"github.com/Joggz/services/business/web/mid"
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// failingWriter is a response writer for a client that went away.
+type failingWriter struct {
+	*httptest.ResponseRecorder
+}
+
+func (fw failingWriter) Write([]byte) (int, error) {
+	return 0, errors.New("write: broken pipe")
+}
+
+func TestErrorsClientGone(t *testing.T) {
+	t.Log("Given the need to survive clients that go away before the error is sent.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the error response can't be written.", testID)
+		{
+			log := zap.NewNop().Sugar()
+			shutdown := make(chan os.Signal, 1)
+			app := web.NewApp(shutdown, log, mid.Errors(log))
+
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				return errors.New("database unavailable")
+			}
+			app.Handle(http.MethodGet, "", "/test", handler)
+
+			r := httptest.NewRequest(http.MethodGet, "/test", nil)
+			app.ServeHTTP(failingWriter{httptest.NewRecorder()}, r)
+
+			select {
+			case <-shutdown:
+				t.Fatalf("\t%s\tTest %d:\tShould not signal shutdown.", failed, testID)
+			default:
+			}
+			t.Logf("\t%s\tTest %d:\tShould not signal shutdown.", success, testID)
+		}
+	}
+}
+
+// newUser is a value decoded and validated from test requests.
+type newUser struct {
+	Name  string `json:"name" validate:"required"`
+	Email string `json:"email" validate:"required,email"`
+}
+
+// Validate checks the value against its validation tags.
+func (nu newUser) Validate() error {
+	return validate.Check(nu)
+}
+
+func TestErrorsDecode(t *testing.T) {
+	tt := []struct {
+		name   string
+		body   string
+		status int
+		fields []string
+	}{
+		{"valid", `{"name":"bill","email":"bill@example.com"}`, http.StatusOK, nil},
+		{"malformed", `{"name":`, http.StatusBadRequest, nil},
+		{"unknown field", `{"name":"bill","email":"bill@example.com","age":42}`, http.StatusBadRequest, nil},
+		{"invalid", `{"email":"bill"}`, http.StatusBadRequest, []string{"name", "email"}},
+	}
+
+	t.Log("Given the need to report bad request bodies to the client.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen sending a %s body.", testID, test.name)
+			{
+				log := zap.NewNop().Sugar()
+				app := web.NewApp(make(chan os.Signal, 1), log, mid.Errors(log))
+
+				handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					var nu newUser
+					if err := web.Decode(r, &nu); err != nil {
+						return err
+					}
+					return web.Respond(ctx, w, nu, http.StatusOK)
+				}
+				app.Handle(http.MethodPost, "", "/test", handler)
+
+				r := httptest.NewRequest(http.MethodPost, "/test", strings.NewReader(test.body))
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != test.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a %d status code : got %d", failed, testID, test.status, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a %d status code.", success, testID, test.status)
+
+				if test.status == http.StatusOK {
+					continue
+				}
+
+				var er validate.ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&er); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to decode the error response : %s", failed, testID, err)
+				}
+				for _, name := range test.fields {
+					if er.Fields[name] == "" {
+						t.Fatalf("\t%s\tTest %d:\tShould report the %s field : got %v", failed, testID, name, er.Fields)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould report the error : %s", success, testID, er.Error)
+			}
+		}
+	}
+}
+
+func TestErrorsRequestError(t *testing.T) {
+	tt := []struct {
+		name   string
+		err    error
+		status int
+		want   string
+	}{
+		{"client", validate.NewRequestError(errors.New("user not found"), http.StatusNotFound), http.StatusNotFound, "user not found"},
+		{"server", validate.NewRequestError(errors.New("pq: password authentication failed"), http.StatusInternalServerError), http.StatusInternalServerError, http.StatusText(http.StatusInternalServerError)},
+		{"unavailable", validate.NewRequestError(errors.New("dial tcp 10.0.0.5:5432: connection refused"), http.StatusServiceUnavailable), http.StatusServiceUnavailable, http.StatusText(http.StatusServiceUnavailable)},
+	}
+
+	t.Log("Given the need to keep internal details out of error responses.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen a handler returns a %s request error.", testID, test.name)
+			{
+				log := zap.NewNop().Sugar()
+				app := web.NewApp(make(chan os.Signal, 1), log, mid.Errors(log))
+
+				handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return test.err
+				}
+				app.Handle(http.MethodGet, "", "/test", handler)
+
+				r := httptest.NewRequest(http.MethodGet, "/test", nil)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != test.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a %d status code : got %d", failed, testID, test.status, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a %d status code.", success, testID, test.status)
+
+				var er validate.ErrorResponse
+				if err := json.NewDecoder(w.Body).Decode(&er); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to decode the error response : %s", failed, testID, err)
+				}
+				if er.Error != test.want {
+					t.Fatalf("\t%s\tTest %d:\tShould respond with %q : got %q", failed, testID, test.want, er.Error)
+				}
+				t.Logf("\t%s\tTest %d:\tShould respond with %q.", success, testID, test.want)
+			}
+		}
+	}
+}
+
+func TestErrorsTimeout(t *testing.T) {
+	t.Log("Given the need to report requests that run past their deadline.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the handler waits on the context past the deadline.", testID)
+		{
+			log := zap.NewNop().Sugar()
+			app := web.NewApp(make(chan os.Signal, 1), log, mid.Errors(log))
+
+			handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+				<-ctx.Done()
+				return ctx.Err()
+			}
+			app.Handle(http.MethodGet, "", "/test", handler, web.Timeout(10*time.Millisecond))
+
+			r := httptest.NewRequest(http.MethodGet, "/test", nil)
+			w := httptest.NewRecorder()
+			app.ServeHTTP(w, r)
+
+			if w.Code != http.StatusGatewayTimeout {
+				t.Fatalf("\t%s\tTest %d:\tShould receive a %d status code : got %d", failed, testID, http.StatusGatewayTimeout, w.Code)
+			}
+			t.Logf("\t%s\tTest %d:\tShould receive a %d status code.", success, testID, http.StatusGatewayTimeout)
+
+			var er validate.ErrorResponse
+			if err := json.NewDecoder(w.Body).Decode(&er); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to decode the error response : %s", failed, testID, err)
+			}
+			if er.Error != web.ErrDeadlineExceeded.Error() {
+				t.Fatalf("\t%s\tTest %d:\tShould report %q : got %q", failed, testID, web.ErrDeadlineExceeded, er.Error)
+			}
+			t.Logf("\t%s\tTest %d:\tShould report %q.", success, testID, web.ErrDeadlineExceeded)
+		}
+	}
+}
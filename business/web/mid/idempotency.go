@@ -0,0 +1,205 @@
+package mid
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+
+	"github.com/Joggz/services/business/sys/validate"
+	"github.com/Joggz/services/foundation/idempotency"
+	"github.com/Joggz/services/foundation/web"
+)
+
+// IdempotencyKeyHeader is the request header clients provide their
+// idempotency key in.
+const IdempotencyKeyHeader = "Idempotency-Key"
+
+// IdempotencyConfig defines how responses to unsafe requests are kept.
+type IdempotencyConfig struct {
+	Store   idempotency.Store
+	TTL     time.Duration
+	Subject KeyFunc
+}
+
+// Idempotency honours the Idempotency-Key header of unsafe requests. The
+// first response for a key is stored and replayed for retries of the same
+// request. Reusing a key for a different request is rejected with a 422.
+// Requests with the same key are processed one at a time.
+func Idempotency(cfg IdempotencyConfig) web.Middleware {
+	subject := cfg.Subject
+	if subject == nil {
+		subject = KeyByIP
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			idemKey := r.Header.Get(IdempotencyKeyHeader)
+			if idemKey == "" || !unsafeMethod(r.Method) {
+				return handler(ctx, w, r)
+			}
+
+			// Keys are only unique for the client that chose them.
+			key := subject(ctx, r) + "|" + idemKey
+
+			body, err := io.ReadAll(r.Body)
+			if err != nil {
+				return err
+			}
+			r.Body = io.NopCloser(bytes.NewReader(body))
+			fingerprint := requestFingerprint(r, body)
+
+			unlock, err := cfg.Store.Lock(ctx, key)
+			if err != nil {
+				return fmt.Errorf("locking idempotency key: %w", err)
+			}
+			defer unlock()
+
+			rec, exists, err := cfg.Store.Get(ctx, key)
+			if err != nil {
+				return fmt.Errorf("getting idempotency record: %w", err)
+			}
+
+			if exists {
+				if rec.Fingerprint != fingerprint {
+					return validate.NewRequestError(errors.New("idempotency key reused for a different request"), http.StatusUnprocessableEntity)
+				}
+				return replay(ctx, w, rec)
+			}
+
+			// Call the next handler capturing what it responds with.
+			rw := recorder{
+				ResponseWriter: w,
+				before:         w.Header().Clone(),
+			}
+			if err := handler(ctx, &rw, r); err != nil {
+
+				// Errors are answered further up the chain and are not
+				// kept, so the client can retry the request.
+				return err
+			}
+
+			if rw.statusCode == 0 {
+				return nil
+			}
+
+			rec = idempotency.Record{
+				Fingerprint: fingerprint,
+				StatusCode:  rw.statusCode,
+				Header:      rw.header,
+				Body:        rw.body.Bytes(),
+			}
+			if err := cfg.Store.Put(ctx, key, rec, cfg.TTL); err != nil {
+				return fmt.Errorf("storing idempotency record: %w", err)
+			}
+
+			return nil
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// unsafeMethod reports if requests with the method change server state.
+func unsafeMethod(method string) bool {
+	switch method {
+	case http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete:
+		return true
+	}
+	return false
+}
+
+// requestFingerprint identifies the request a key was first used with.
+func requestFingerprint(r *http.Request, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, r.Method+" "+r.URL.RequestURI()+"\n")
+	h.Write(body)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// replay writes the stored response to the client.
+func replay(ctx context.Context, w http.ResponseWriter, rec idempotency.Record) error {
+	if err := web.SetStatusCode(ctx, rec.StatusCode); err != nil {
+		return err
+	}
+
+	for k, vs := range rec.Header {
+		w.Header()[k] = vs
+	}
+	w.Header().Set("Idempotent-Replayed", "true")
+
+	w.WriteHeader(rec.StatusCode)
+	if _, err := w.Write(rec.Body); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// recorder captures the response written by a handler while passing it
+// through to the client.
+type recorder struct {
+	http.ResponseWriter
+	before     http.Header
+	header     http.Header
+	statusCode int
+	body       bytes.Buffer
+}
+
+// WriteHeader captures the status code and the headers set by the handler.
+// Headers that were already set before the handler ran belong to other
+// middleware and are not part of the stored response.
+func (rw *recorder) WriteHeader(statusCode int) {
+	if rw.statusCode != 0 {
+		return
+	}
+	rw.statusCode = statusCode
+
+	rw.header = make(http.Header)
+	for k, vs := range rw.Header() {
+		if !equalValues(rw.before[k], vs) {
+			rw.header[k] = append([]string(nil), vs...)
+		}
+	}
+
+	rw.ResponseWriter.WriteHeader(statusCode)
+}
+
+// Write captures the body.
+func (rw *recorder) Write(p []byte) (int, error) {
+	if rw.statusCode == 0 {
+		rw.WriteHeader(http.StatusOK)
+	}
+	rw.body.Write(p)
+	return rw.ResponseWriter.Write(p)
+}
+
+// Unwrap returns the original response writer.
+func (rw *recorder) Unwrap() http.ResponseWriter {
+	return rw.ResponseWriter
+}
+
+// equalValues reports if two sets of header values are the same.
+func equalValues(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,118 @@
+package mid_test
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Joggz/services/business/web/mid"
+	"github.com/Joggz/services/foundation/idempotency"
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+func TestIdempotency(t *testing.T) {
+	tt := []struct {
+		name     string
+		method   string
+		path     string
+		key      string
+		body     string
+		status   int
+		calls    int
+		replayed bool
+	}{
+		{"the first request", http.MethodPost, "/products", "k1", `{"name":"a"}`, http.StatusCreated, 1, false},
+		{"a retry", http.MethodPost, "/products", "k1", `{"name":"a"}`, http.StatusCreated, 1, true},
+		{"the key reused for another body", http.MethodPost, "/products", "k1", `{"name":"b"}`, http.StatusUnprocessableEntity, 1, false},
+		{"the key reused for another path", http.MethodPost, "/users", "k1", `{"name":"a"}`, http.StatusUnprocessableEntity, 1, false},
+		{"no key", http.MethodPost, "/products", "", `{"name":"a"}`, http.StatusCreated, 2, false},
+		{"a safe method", http.MethodGet, "/products", "k1", ``, http.StatusOK, 3, false},
+		{"a failed request", http.MethodPost, "/products", "k2", `fail`, http.StatusInternalServerError, 4, false},
+		{"a retry of a failed request", http.MethodPost, "/products", "k2", `fail`, http.StatusInternalServerError, 5, false},
+		{"a request with a query", http.MethodPost, "/products?dryRun=true", "k3", `{"name":"a"}`, http.StatusCreated, 6, false},
+		{"the key reused for another query", http.MethodPost, "/products", "k3", `{"name":"a"}`, http.StatusUnprocessableEntity, 6, false},
+	}
+
+	log := zap.NewNop().Sugar()
+	app := web.NewApp(make(chan os.Signal, 1), log, mid.Errors(log), mid.Idempotency(mid.IdempotencyConfig{
+		Store: idempotency.NewMemoryStore(),
+		TTL:   time.Hour,
+	}))
+
+	var calls int
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		calls++
+
+		body, err := io.ReadAll(r.Body)
+		if err != nil {
+			return err
+		}
+		if string(body) == "fail" {
+			return errors.New("database unavailable")
+		}
+
+		if r.Method == http.MethodGet {
+			return web.Respond(ctx, w, calls, http.StatusOK)
+		}
+
+		w.Header().Set("Location", "/products/"+strconv.Itoa(calls))
+		return web.Respond(ctx, w, calls, http.StatusCreated)
+	}
+	app.Handle(http.MethodGet, "", "/products", handler)
+	app.Handle(http.MethodPost, "", "/products", handler)
+	app.Handle(http.MethodPost, "", "/users", handler)
+
+	t.Log("Given the need to answer retried requests without repeating the work.")
+	{
+		var first *httptest.ResponseRecorder
+
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen sending %s.", testID, test.name)
+			{
+				r := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+				if test.key != "" {
+					r.Header.Set(mid.IdempotencyKeyHeader, test.key)
+				}
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != test.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a %d status code : got %d", failed, testID, test.status, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a %d status code.", success, testID, test.status)
+
+				if calls != test.calls {
+					t.Fatalf("\t%s\tTest %d:\tShould have called the handler %d times : got %d", failed, testID, test.calls, calls)
+				}
+				t.Logf("\t%s\tTest %d:\tShould have called the handler %d times.", success, testID, test.calls)
+
+				replayed := w.Header().Get("Idempotent-Replayed") == "true"
+				if replayed != test.replayed {
+					t.Fatalf("\t%s\tTest %d:\tShould be replayed %v : got %v", failed, testID, test.replayed, replayed)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be replayed %v.", success, testID, test.replayed)
+
+				if testID == 0 {
+					first = w
+				}
+				if test.replayed {
+					if w.Body.String() != first.Body.String() || w.Header().Get("Location") != first.Header().Get("Location") {
+						t.Fatalf("\t%s\tTest %d:\tShould replay the first response : got %q %q", failed, testID, w.Body.String(), w.Header().Get("Location"))
+					}
+					if traceID := w.Header().Get(web.TraceIDHeader); traceID == "" || traceID == first.Header().Get(web.TraceIDHeader) {
+						t.Fatalf("\t%s\tTest %d:\tShould keep the trace id of the retry : got %q", failed, testID, traceID)
+					}
+					t.Logf("\t%s\tTest %d:\tShould replay the first response.", success, testID)
+				}
+			}
+		}
+	}
+}
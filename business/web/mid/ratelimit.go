@@ -0,0 +1,123 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/Joggz/services/business/sys/metrics"
+	"github.com/Joggz/services/business/sys/validate"
+	"github.com/Joggz/services/foundation/ratelimit"
+	"github.com/Joggz/services/foundation/web"
+)
+
+// KeyFunc returns the key identifying the client making the request.
+type KeyFunc func(ctx context.Context, r *http.Request) string
+
+// KeyByIP identifies clients by the ip address of the connection.
+func KeyByIP(ctx context.Context, r *http.Request) string {
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return "ip:" + r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// KeyByAPIKey identifies clients by the api key authentication middleware
+// set in the context, falling back to the ip address for requests without
+// one. The raw header is never used, since a client could send a new key
+// with every request to get a fresh allowance. Until middleware sets the
+// authenticated key, every request is limited by ip address.
+func KeyByAPIKey(ctx context.Context, r *http.Request) string {
+	if key := web.GetAPIKey(ctx); key != "" {
+		return "apikey:" + key
+	}
+	return KeyByIP(ctx, r)
+}
+
+// KeyBySubject identifies clients by the authenticated subject the specified
+// function finds in the context, falling back to the ip address for
+// requests without one.
+func KeyBySubject(subject func(ctx context.Context) string) KeyFunc {
+	f := func(ctx context.Context, r *http.Request) string {
+		if sub := subject(ctx); sub != "" {
+			return "subject:" + sub
+		}
+		return KeyByIP(ctx, r)
+	}
+
+	return f
+}
+
+// ParseKeyFunc returns the KeyFunc for the specified name, which is one of
+// "ip", "apikey" or "subject". The api key is the one authenticated by
+// middleware, see KeyByAPIKey. The subject is taken from the verified
+// client certificate of mutual TLS requests.
+func ParseKeyFunc(name string) (KeyFunc, error) {
+	switch name {
+	case "ip":
+		return KeyByIP, nil
+	case "apikey":
+		return KeyByAPIKey, nil
+	case "subject":
+		return KeyBySubject(web.GetClientSubject), nil
+	}
+	return nil, fmt.Errorf("unknown rate limit key %q", name)
+}
+
+// RateLimitConfig defines the rate limit of a route group. A Rate of zero
+// disables limiting.
+type RateLimitConfig struct {
+	Rate  float64
+	Burst int
+	Key   KeyFunc
+}
+
+// RateLimit rejects requests from clients that have used up their allowance
+// of requests with a 429 status.
+func RateLimit(cfg RateLimitConfig) web.Middleware {
+	limiter := ratelimit.New(cfg.Rate, cfg.Burst)
+
+	key := cfg.Key
+	if key == nil {
+		key = KeyByIP
+	}
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			res := limiter.Allow(key(ctx, r))
+
+			w.Header().Set("RateLimit-Limit", strconv.Itoa(res.Limit))
+			w.Header().Set("RateLimit-Remaining", strconv.Itoa(res.Remaining))
+			w.Header().Set("RateLimit-Reset", seconds(res.Reset))
+
+			if !res.Allowed {
+				metrics.AddRateLimited(ctx)
+
+				w.Header().Set("Retry-After", seconds(res.RetryAfter))
+				return validate.NewRequestError(errors.New("rate limit exceeded"), http.StatusTooManyRequests)
+			}
+
+			// Call the next handler.
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// seconds formats a duration as whole seconds, rounding up so clients
+// never retry too early.
+func seconds(d time.Duration) string {
+	return strconv.FormatInt(int64(math.Ceil(d.Seconds())), 10)
+}
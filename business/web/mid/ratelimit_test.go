@@ -0,0 +1,72 @@
+package mid_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Joggz/services/business/web/mid"
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+// authenticate returns middleware that authenticates the single valid key.
+func authenticate(valid string) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			if key := r.Header.Get("X-API-Key"); key == valid {
+				if err := web.SetAPIKey(ctx, key); err != nil {
+					return err
+				}
+			}
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+func TestKeyByAPIKey(t *testing.T) {
+	tt := []struct {
+		name   string
+		apiKey string
+		want   string
+	}{
+		{"no key", "", "ip:192.0.2.1"},
+		{"an unauthenticated key", "random", "ip:192.0.2.1"},
+		{"an authenticated key", "valid", "apikey:valid"},
+	}
+
+	t.Log("Given the need to rate limit clients by their api key.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen sending %s.", testID, test.name)
+			{
+				var got string
+				app := web.NewApp(make(chan os.Signal, 1), zap.NewNop().Sugar(), authenticate("valid"))
+
+				handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					got = mid.KeyByAPIKey(ctx, r)
+					return nil
+				}
+				app.Handle(http.MethodGet, "", "/test", handler)
+
+				r := httptest.NewRequest(http.MethodGet, "/test", nil)
+				r.RemoteAddr = "192.0.2.1:1234"
+				if test.apiKey != "" {
+					r.Header.Set("X-API-Key", test.apiKey)
+				}
+				app.ServeHTTP(httptest.NewRecorder(), r)
+
+				if got != test.want {
+					t.Fatalf("\t%s\tTest %d:\tShould be keyed by %q : got %q", failed, testID, test.want, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould be keyed by %q.", success, testID, test.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,47 @@
+package mid
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/Joggz/services/business/sys/metrics"
+	"github.com/Joggz/services/business/sys/validate"
+	"github.com/Joggz/services/foundation/limiter"
+	"github.com/Joggz/services/foundation/web"
+)
+
+// Shed bounds the number of requests processed at once. Requests that
+// can't get a slot within the limiter's queue rules are rejected with a 503
+// so the service sheds load instead of letting requests pile up until they
+// time out.
+func Shed(cfg limiter.Config) web.Middleware {
+	l := limiter.New(cfg)
+
+	// This is the actual middleware function to be executed.
+	m := func(handler web.Handler) web.Handler {
+
+		// Create the handler that will be attached in the middleware chain.
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			release, err := l.Acquire(ctx)
+			if err != nil {
+				metrics.AddShed(ctx)
+
+				w.Header().Set("Retry-After", "1")
+				return validate.NewRequestError(errors.New("service overloaded"), http.StatusServiceUnavailable)
+			}
+
+			// Call the next handler and report how long it took.
+			start := time.Now()
+			err = handler(ctx, w, r)
+			release(time.Since(start))
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
@@ -0,0 +1,202 @@
+// Package config layers a YAML or JSON configuration file, with optional
+// per-environment profile overlays, underneath the environment variables and
+// command line flags handled by the conf package.
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/ardanlabs/conf"
+)
+
+// The sources a setting can come from, in order of increasing precedence.
+const (
+	SourceNone    = "none"
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceProfile = "profile"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+)
+
+// Options selects the configuration file and the profile within it to
+// apply. Both are optional, though a profile requires a file.
+type Options struct {
+	File    string
+	Profile string
+}
+
+// Setting describes the effective value of a configuration field and the
+// source that provided it. The source is one of the Source constants,
+// qualified with the file, profile, variable or flag for the sources that
+// have one, like "env:SALES_WEB_API_HOST".
+type Setting struct {
+	Key    string
+	Value  string
+	Source string
+}
+
+// Parse parses the configuration into the provided struct like conf.Parse,
+// with the file and then the profile applied between the defaults and the
+// environment. Any key in the file that doesn't match a field is an error.
+func Parse(args []string, prefix string, cfg any, opts Options) ([]Setting, error) {
+	if opts.File == "" && opts.Profile != "" {
+		return nil, fmt.Errorf("config profile %q requires a config file", opts.Profile)
+	}
+
+	base, profiles, err := load(opts.File)
+	if err != nil {
+		return nil, err
+	}
+
+	profile := &document{name: opts.Profile}
+	if opts.Profile != "" {
+		p, exists := profiles[opts.Profile]
+		if !exists {
+			return nil, fmt.Errorf("config file %s: profile %q not found", opts.File, opts.Profile)
+		}
+		profile = p
+	}
+
+	if err := conf.Parse(args, prefix, cfg, base, profile); err != nil {
+		return nil, fmt.Errorf("parsing config: %w", err)
+	}
+
+	// Every profile is checked, not just the one in use, so mistakes are
+	// found before the profile is needed.
+	if keys := base.unknown(base.fields); len(keys) > 0 {
+		return nil, fmt.Errorf("config file %s: unknown keys: %s", opts.File, strings.Join(keys, ", "))
+	}
+	names := make([]string, 0, len(profiles))
+	for name := range profiles {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		if keys := profiles[name].unknown(base.fields); len(keys) > 0 {
+			return nil, fmt.Errorf("config file %s: profile %q: unknown keys: %s", opts.File, name, strings.Join(keys, ", "))
+		}
+	}
+
+	env := environ(prefix)
+	flags := flagSet(args)
+
+	settings := make([]Setting, 0, len(base.fields))
+	for _, fld := range base.fields {
+		if fld.Options.Noprint {
+			continue
+		}
+
+		key := strings.ToLower(strings.Join(fld.FlagKey, "-"))
+		envKey := strings.ToUpper(strings.Join(fld.EnvKey, "_"))
+
+		source := SourceNone
+		switch {
+		case flags[key] || (fld.Options.ShortFlagChar != 0 && flags[strings.ToLower(string(fld.Options.ShortFlagChar))]):
+			source = SourceFlag + ":--" + key
+		case env[envKey]:
+			source = SourceEnv + ":" + envName(prefix, envKey)
+		case profile.provided(fld):
+			source = SourceProfile + ":" + profile.name
+		case base.provided(fld):
+			source = SourceFile + ":" + base.name
+		case fld.Options.DefaultVal != "":
+			source = SourceDefault
+		}
+
+		value := fmt.Sprintf("%v", fld.Field.Interface())
+		if fld.Options.Mask {
+			value = "xxxxxx"
+		}
+
+		settings = append(settings, Setting{
+			Key:    key,
+			Value:  value,
+			Source: source,
+		})
+	}
+
+	return settings, nil
+}
+
+// Dump formats the settings as a table with one setting per line.
+func Dump(settings []Setting) string {
+	var b strings.Builder
+
+	tw := tabwriter.NewWriter(&b, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(tw, "KEY\tVALUE\tSOURCE")
+	for _, s := range settings {
+		fmt.Fprintf(tw, "%s\t%s\t%s\n", s.Key, s.Value, s.Source)
+	}
+	tw.Flush()
+
+	return b.String()
+}
+
+// =============================================================================
+
+// environ returns the set of variables in the environment under the prefix,
+// keyed the same way the conf package keys them.
+func environ(prefix string) map[string]bool {
+	uspace := strings.ToUpper(prefix) + "_"
+	if prefix == "" {
+		uspace = ""
+	}
+
+	m := make(map[string]bool)
+	for _, kv := range os.Environ() {
+		if !strings.HasPrefix(kv, uspace) {
+			continue
+		}
+
+		idx := strings.Index(kv, "=")
+		m[strings.ToUpper(strings.TrimPrefix(kv[:idx], uspace))] = true
+	}
+
+	return m
+}
+
+// envName returns the full name of the environment variable for the key.
+func envName(prefix string, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return strings.ToUpper(prefix) + "_" + key
+}
+
+// flagSet returns the set of flag names present in the arguments, following
+// the same rules the conf package uses to parse them.
+func flagSet(args []string) map[string]bool {
+	m := make(map[string]bool)
+
+	for len(args) > 0 {
+		s := args[0]
+		if len(s) < 2 || s[0] != '-' {
+			break
+		}
+
+		name := strings.TrimPrefix(s[1:], "-")
+		if name == "" {
+			break
+		}
+		args = args[1:]
+
+		if i := strings.Index(name, "="); i > 0 {
+			m[name[:i]] = true
+			continue
+		}
+
+		// A value can follow the flag as the next argument.
+		if len(args) > 0 && len(args[0]) > 0 && args[0][0] != '-' {
+			args = args[1:]
+		}
+		m[name] = true
+	}
+
+	return m
+}
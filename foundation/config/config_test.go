@@ -0,0 +1,170 @@
+package config_test
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/Joggz/services/foundation/config"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// cfg is the configuration parsed by the tests.
+type cfg struct {
+	Web struct {
+		ReadTimeout  time.Duration `conf:"default:5s"`
+		WriteTimeout time.Duration `conf:"default:10s"`
+		IdleTimeout  time.Duration `conf:"default:120s"`
+		APIHost      string        `conf:"default:0.0.0.0:3000"`
+		DebugHost    string        `conf:"default:0.0.0.0:4000"`
+		Origins      []string
+	}
+}
+
+// file is a config file setting every field below the environment and
+// flags, with profiles to layer on top of it.
+const file = `
+web:
+  readTimeout: 1s
+  write_timeout: 2s
+  idle-timeout: 3s
+  apiHost: file:3000
+  debugHost: file:4000
+  origins:
+    - https://a.example.com
+    - https://b.example.com
+profiles:
+  dev:
+    web:
+      writeTimeout: 20s
+      idleTimeout: 30s
+      apiHost: dev:3000
+  prod:
+    web:
+      idleTimeout: 300s
+`
+
+// writeFile writes the config file into a temporary directory.
+func writeFile(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "config.yaml")
+	if err := os.WriteFile(path, []byte(data), 0600); err != nil {
+		t.Fatalf("\t%s\tShould be able to write the config file : %s", failed, err)
+	}
+	return path
+}
+
+func TestParsePrecedence(t *testing.T) {
+	path := writeFile(t, file)
+
+	t.Setenv("TEST_WEB_API_HOST", "env:3000")
+	t.Setenv("TEST_WEB_DEBUG_HOST", "env:4000")
+	args := []string{"--web-debug-host", "flag:4000"}
+
+	t.Log("Given the need to layer the config file underneath the environment and flags.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen every source sets a field.", testID)
+		{
+			var c cfg
+			settings, err := config.Parse(args, "TEST", &c, config.Options{File: path, Profile: "dev"})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to parse the config : %s", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to parse the config.", success, testID)
+
+			sources := make(map[string]config.Setting)
+			for _, s := range settings {
+				sources[s.Key] = s
+			}
+
+			tt := []struct {
+				key    string
+				value  string
+				source string
+			}{
+				{"web-read-timeout", "1s", "file:" + path},
+				{"web-write-timeout", "20s", "profile:dev"},
+				{"web-idle-timeout", "30s", "profile:dev"},
+				{"web-api-host", "env:3000", "env:TEST_WEB_API_HOST"},
+				{"web-debug-host", "flag:4000", "flag:--web-debug-host"},
+				{"web-origins", "[https://a.example.com https://b.example.com]", "file:" + path},
+			}
+
+			for _, test := range tt {
+				s := sources[test.key]
+				if s.Value != test.value || s.Source != test.source {
+					t.Fatalf("\t%s\tTest %d:\tShould take %s from %s as %s : got %s from %s", failed, testID, test.key, test.source, test.value, s.Value, s.Source)
+				}
+				t.Logf("\t%s\tTest %d:\tShould take %s from %s.", success, testID, test.key, test.source)
+			}
+
+			if c.Web.IdleTimeout != 30*time.Second || c.Web.DebugHost != "flag:4000" || len(c.Web.Origins) != 2 {
+				t.Fatalf("\t%s\tTest %d:\tShould set the effective values : got %+v", failed, testID, c.Web)
+			}
+			t.Logf("\t%s\tTest %d:\tShould set the effective values.", success, testID)
+		}
+
+		testID = 1
+		t.Logf("\tTest %d:\tWhen there is no config file.", testID)
+		{
+			var c cfg
+			settings, err := config.Parse(nil, "TEST", &c, config.Options{})
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to parse the config : %s", failed, testID, err)
+			}
+
+			for _, s := range settings {
+				if s.Key == "web-read-timeout" && (s.Value != "5s" || s.Source != config.SourceDefault) {
+					t.Fatalf("\t%s\tTest %d:\tShould use the default : got %s from %s", failed, testID, s.Value, s.Source)
+				}
+				if s.Key == "web-origins" && s.Source != config.SourceNone {
+					t.Fatalf("\t%s\tTest %d:\tShould report no source : got %s", failed, testID, s.Source)
+				}
+			}
+			t.Logf("\t%s\tTest %d:\tShould use the defaults.", success, testID)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tt := []struct {
+		name    string
+		file    string
+		profile string
+		err     string
+	}{
+		{"an unknown key", "web:\n  readTimeot: 1s\n", "", "unknown keys: web.readTimeot"},
+		{"an unknown key in another profile", "profiles:\n  dev:\n    web:\n      apiHost: dev\n  prod:\n    bogus: 1\n", "dev", `profile "prod": unknown keys: bogus`},
+		{"a duplicate key", "web:\n  readTimeout: 1s\n  read_timeout: 2s\n", "", "duplicates key web.readTimeout"},
+		{"a missing profile", "web:\n  readTimeout: 1s\n", "staging", `profile "staging" not found`},
+		{"a bad value", "web:\n  readTimeout: soon\n", "", "ReadTimeout"},
+		{"a profile without a file", "", "dev", "requires a config file"},
+	}
+
+	t.Log("Given the need to reject config files with mistakes.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen the config file has %s.", testID, test.name)
+			{
+				var path string
+				if test.file != "" {
+					path = writeFile(t, test.file)
+				}
+
+				var c cfg
+				_, err := config.Parse(nil, "TEST", &c, config.Options{File: path, Profile: test.profile})
+				if err == nil || !strings.Contains(err.Error(), test.err) {
+					t.Fatalf("\t%s\tTest %d:\tShould report %q : got %v", failed, testID, test.err, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould report %q.", success, testID, test.err)
+			}
+		}
+	}
+}
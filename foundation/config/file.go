@@ -0,0 +1,220 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/ardanlabs/conf"
+	"gopkg.in/yaml.v3"
+)
+
+// ProfilesKey is the top level key in the file that holds the profile
+// overlays, keyed by profile name such as dev, staging or prod.
+const ProfilesKey = "profiles"
+
+// value is a single setting found in the file.
+type value struct {
+	path string
+	text string
+	null bool
+}
+
+// document is a conf.Sourcer over the settings in the file or one of its
+// profiles. Keys match fields the same way flags do, ignoring case, dashes
+// and underscores, so web.readTimeout, web.read_timeout and
+// web.read-timeout all set the --web-read-timeout field.
+type document struct {
+	name   string
+	values map[string]*value
+	fields []conf.Field
+}
+
+// load reads the file and every profile defined within it. Without a file
+// the base document is empty, so it can always be used as a source.
+func load(path string) (*document, map[string]*document, error) {
+	base := document{name: path, values: make(map[string]*value)}
+	profiles := make(map[string]*document)
+
+	if path == "" {
+		return &base, profiles, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("reading config file: %w", err)
+	}
+
+	// JSON is a subset of YAML, so the one decoder serves both formats.
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		return nil, nil, fmt.Errorf("decoding config file %s: %w", path, err)
+	}
+	if len(root.Content) == 0 {
+		return &base, profiles, nil
+	}
+
+	node := resolve(root.Content[0])
+	if node.Kind != yaml.MappingNode {
+		return nil, nil, fmt.Errorf("config file %s: document must be a mapping", path)
+	}
+
+	// Pull the profiles out of the top level before flattening the rest.
+	content := make([]*yaml.Node, 0, len(node.Content))
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		if normalize(node.Content[i].Value) != ProfilesKey {
+			content = append(content, node.Content[i], node.Content[i+1])
+			continue
+		}
+
+		list := resolve(node.Content[i+1])
+		if list.Kind != yaml.MappingNode {
+			return nil, nil, fmt.Errorf("config file %s: %s must be a mapping", path, ProfilesKey)
+		}
+
+		for j := 0; j+1 < len(list.Content); j += 2 {
+			name := list.Content[j].Value
+
+			overlay := resolve(list.Content[j+1])
+			if overlay.Kind != yaml.MappingNode {
+				return nil, nil, fmt.Errorf("config file %s: profile %q must be a mapping", path, name)
+			}
+
+			profile := document{name: name, values: make(map[string]*value)}
+			if err := flatten(overlay, "", profile.values); err != nil {
+				return nil, nil, fmt.Errorf("config file %s: profile %q: %w", path, name, err)
+			}
+			profiles[name] = &profile
+		}
+	}
+
+	if err := flatten(&yaml.Node{Kind: yaml.MappingNode, Content: content}, "", base.values); err != nil {
+		return nil, nil, fmt.Errorf("config file %s: %w", path, err)
+	}
+
+	return &base, profiles, nil
+}
+
+// Source implements the conf.Sourcer interface. It returns the value stored
+// in the document for the field, recording the field as it goes.
+func (d *document) Source(fld conf.Field) (string, bool) {
+	d.fields = append(d.fields, fld)
+
+	v, exists := d.values[fieldKey(fld)]
+	if !exists || v.null {
+		return "", false
+	}
+
+	return v.text, true
+}
+
+// provided reports whether the document supplies a value for the field.
+func (d *document) provided(fld conf.Field) bool {
+	v, exists := d.values[fieldKey(fld)]
+	return exists && !v.null
+}
+
+// unknown returns the sorted keys in the document that don't match any of
+// the fields.
+func (d *document) unknown(fields []conf.Field) []string {
+	known := make(map[string]bool, len(fields))
+	for _, fld := range fields {
+		known[fieldKey(fld)] = true
+	}
+
+	var keys []string
+	for key, v := range d.values {
+		if !known[key] {
+			keys = append(keys, v.path)
+		}
+	}
+	sort.Strings(keys)
+
+	return keys
+}
+
+// =============================================================================
+
+// flatten walks the mapping and adds a value for every leaf to the set,
+// keyed by the normalized path to it. Sequences of scalars become a list
+// separated by semicolons, which is how the conf package parses slices.
+func flatten(node *yaml.Node, path string, values map[string]*value) error {
+	for i := 0; i+1 < len(node.Content); i += 2 {
+		key := node.Content[i].Value
+		child := resolve(node.Content[i+1])
+
+		p := key
+		if path != "" {
+			p = path + "." + key
+		}
+
+		switch child.Kind {
+		case yaml.MappingNode:
+			if err := flatten(child, p, values); err != nil {
+				return err
+			}
+
+		case yaml.SequenceNode:
+			items := make([]string, len(child.Content))
+			for j, item := range child.Content {
+				item = resolve(item)
+				if item.Kind != yaml.ScalarNode {
+					return fmt.Errorf("key %s: list items must be scalar values", p)
+				}
+				if strings.Contains(item.Value, ";") {
+					return fmt.Errorf("key %s: list items can't contain a semicolon", p)
+				}
+				items[j] = item.Value
+			}
+			if err := add(values, p, &value{path: p, text: strings.Join(items, ";")}); err != nil {
+				return err
+			}
+
+		case yaml.ScalarNode:
+			v := value{
+				path: p,
+				text: child.Value,
+				null: child.Tag == "!!null",
+			}
+			if err := add(values, p, &v); err != nil {
+				return err
+			}
+
+		default:
+			return fmt.Errorf("key %s: unsupported value", p)
+		}
+	}
+
+	return nil
+}
+
+// add stores the value, rejecting paths that normalize to the same key.
+func add(values map[string]*value, path string, v *value) error {
+	key := normalize(path)
+	if prev, exists := values[key]; exists {
+		return fmt.Errorf("key %s: duplicates key %s", path, prev.path)
+	}
+	values[key] = v
+	return nil
+}
+
+// resolve follows any alias to the node it refers to.
+func resolve(node *yaml.Node) *yaml.Node {
+	for node.Kind == yaml.AliasNode && node.Alias != nil {
+		node = node.Alias
+	}
+	return node
+}
+
+// fieldKey returns the key the field is stored under in a document.
+func fieldKey(fld conf.Field) string {
+	return normalize(strings.Join(fld.FlagKey, ""))
+}
+
+// normalize lower cases the key and strips the separators so keys can be
+// written in whatever style suits the file.
+func normalize(key string) string {
+	r := strings.NewReplacer(".", "", "-", "", "_", "")
+	return strings.ToLower(r.Replace(key))
+}
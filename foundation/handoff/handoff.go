@@ -0,0 +1,246 @@
+// Package handoff supports restarting the service without dropping
+// connections by passing open listeners to a new process. Listeners are
+// passed using the systemd socket activation protocol, so the same code
+// accepts sockets from systemd.
+//
+// Systemd names each socket after its socket unit unless told otherwise,
+// so the socket units must set FileDescriptorName to the names the service
+// asks for, like:
+//
+//	[Socket]
+//	ListenStream=3000
+//	FileDescriptorName=api
+package handoff
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Set of environment variables used to pass listeners and to report
+// readiness back to the parent process.
+const (
+	envListenPID     = "LISTEN_PID"
+	envListenFDs     = "LISTEN_FDS"
+	envListenFDNames = "LISTEN_FDNAMES"
+	envReadyFD       = "HANDOFF_READY_FD"
+)
+
+// listenFDsStart is the first file descriptor passed by the protocol.
+const listenFDsStart = 3
+
+// Listener is a listener and the name it is passed under.
+type Listener struct {
+	Name     string
+	Listener net.Listener
+}
+
+// Inherited returns the listeners passed to the process keyed by name.
+// Names come from LISTEN_FDNAMES and default to the specified names in
+// order when it isn't set. Every listener passed must carry one of the
+// specified names, otherwise an error is returned rather than leaving a
+// socket nothing serves. The variables are cleared so they are not passed
+// on to other processes.
+func Inherited(names ...string) (map[string]net.Listener, error) {
+	defer func() {
+		os.Unsetenv(envListenPID)
+		os.Unsetenv(envListenFDs)
+		os.Unsetenv(envListenFDNames)
+	}()
+
+	fds := os.Getenv(envListenFDs)
+	if fds == "" {
+		return nil, nil
+	}
+
+	// Systemd sets the pid the sockets are meant for. Our own handoff
+	// doesn't know the pid of the child in advance and leaves it unset.
+	if pid := os.Getenv(envListenPID); pid != "" && pid != strconv.Itoa(os.Getpid()) {
+		return nil, nil
+	}
+
+	n, err := strconv.Atoi(fds)
+	if err != nil {
+		return nil, fmt.Errorf("parsing %s: %w", envListenFDs, err)
+	}
+
+	fdNames := names
+	if v := os.Getenv(envListenFDNames); v != "" {
+		fdNames = strings.Split(v, ":")
+	}
+
+	wanted := make(map[string]bool, len(names))
+	for _, name := range names {
+		wanted[name] = true
+	}
+
+	listeners := make(map[string]net.Listener, n)
+	closeAll := func() {
+		for _, ln := range listeners {
+			ln.Close()
+		}
+	}
+
+	for i := 0; i < n; i++ {
+		name := strconv.Itoa(i)
+		if i < len(fdNames) {
+			name = fdNames[i]
+		}
+
+		f := os.NewFile(uintptr(listenFDsStart+i), name)
+		ln, err := net.FileListener(f)
+		f.Close()
+		if err != nil {
+			closeAll()
+			return nil, fmt.Errorf("inheriting listener %q: %w", name, err)
+		}
+
+		if !wanted[name] {
+			ln.Close()
+			closeAll()
+			return nil, fmt.Errorf("inherited listener %q doesn't match any of %s, set FileDescriptorName on the socket unit", name, strings.Join(names, ", "))
+		}
+		if _, exists := listeners[name]; exists {
+			ln.Close()
+			closeAll()
+			return nil, fmt.Errorf("inherited listener %q passed more than once", name)
+		}
+
+		listeners[name] = ln
+	}
+
+	return listeners, nil
+}
+
+// Listen returns the inherited listener with the specified name or starts
+// listening on the address when there is none.
+func Listen(inherited map[string]net.Listener, name string, addr string) (net.Listener, error) {
+	if ln, exists := inherited[name]; exists {
+		return ln, nil
+	}
+	return net.Listen("tcp", addr)
+}
+
+// Ready tells the parent process, if any, that this process is serving.
+func Ready() error {
+	v := os.Getenv(envReadyFD)
+	if v == "" {
+		return nil
+	}
+	os.Unsetenv(envReadyFD)
+
+	fd, err := strconv.Atoi(v)
+	if err != nil {
+		return fmt.Errorf("parsing %s: %w", envReadyFD, err)
+	}
+
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+
+	if _, err := f.Write([]byte{1}); err != nil {
+		return fmt.Errorf("reporting ready: %w", err)
+	}
+
+	return nil
+}
+
+// Upgrade starts a new copy of the executable with the same arguments,
+// passing it the listeners, and waits for it to report it is ready. The
+// child is killed if it doesn't become ready within the timeout.
+func Upgrade(timeout time.Duration, listeners ...Listener) (*os.Process, error) {
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("locating executable: %w", err)
+	}
+
+	files := make([]*os.File, 0, len(listeners)+1)
+	defer func() {
+		for _, f := range files {
+			f.Close()
+		}
+	}()
+
+	names := make([]string, 0, len(listeners))
+	for _, l := range listeners {
+		fl, ok := l.Listener.(interface{ File() (*os.File, error) })
+		if !ok {
+			return nil, fmt.Errorf("listener %q can't be passed on", l.Name)
+		}
+
+		f, err := fl.File()
+		if err != nil {
+			return nil, fmt.Errorf("getting file for listener %q: %w", l.Name, err)
+		}
+		files = append(files, f)
+		names = append(names, l.Name)
+	}
+
+	readyR, readyW, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("creating ready pipe: %w", err)
+	}
+	defer readyR.Close()
+	files = append(files, readyW)
+
+	env := make([]string, 0, len(os.Environ())+3)
+	for _, kv := range os.Environ() {
+		switch {
+		case strings.HasPrefix(kv, envListenPID+"="),
+			strings.HasPrefix(kv, envListenFDs+"="),
+			strings.HasPrefix(kv, envListenFDNames+"="),
+			strings.HasPrefix(kv, envReadyFD+"="):
+			continue
+		}
+		env = append(env, kv)
+	}
+	env = append(env,
+		envListenFDs+"="+strconv.Itoa(len(listeners)),
+		envListenFDNames+"="+strings.Join(names, ":"),
+		envReadyFD+"="+strconv.Itoa(listenFDsStart+len(listeners)),
+	)
+
+	attr := os.ProcAttr{
+		Env:   env,
+		Files: append([]*os.File{os.Stdin, os.Stdout, os.Stderr}, files...),
+	}
+
+	child, err := os.StartProcess(exe, os.Args, &attr)
+	if err != nil {
+		return nil, fmt.Errorf("starting process: %w", err)
+	}
+
+	// Only the child holds the write end now, so the read fails if the
+	// child exits before reporting ready.
+	readyW.Close()
+	files = files[:len(files)-1]
+
+	ready := make(chan error, 1)
+	go func() {
+		_, err := readyR.Read(make([]byte, 1))
+		if errors.Is(err, io.EOF) {
+			err = errors.New("process exited before it was ready")
+		}
+		ready <- err
+	}()
+
+	select {
+	case err := <-ready:
+		if err != nil {
+			child.Kill()
+			child.Wait()
+			return nil, err
+		}
+		return child, nil
+
+	case <-time.After(timeout):
+		child.Kill()
+		child.Wait()
+		return nil, errors.New("timed out waiting for process to be ready")
+	}
+}
@@ -0,0 +1,94 @@
+package handoff_test
+
+import (
+	"net"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/Joggz/services/foundation/handoff"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// TestInheritedProcess is run in a child process by TestInherited, with
+// listeners passed the way systemd passes them. It reports the names it
+// inherited, or the error, on stdout.
+func TestInheritedProcess(t *testing.T) {
+	if os.Getenv("HANDOFF_TEST_CHILD") == "" {
+		t.Skip("run by TestInherited")
+	}
+
+	listeners, err := handoff.Inherited("api", "debug")
+	if err != nil {
+		os.Stdout.WriteString("error: " + err.Error() + "\n")
+		return
+	}
+
+	names := make([]string, 0, len(listeners))
+	for name := range listeners {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	os.Stdout.WriteString(strings.Join(names, ",") + "\n")
+}
+
+func TestInherited(t *testing.T) {
+	tt := []struct {
+		name    string
+		fdNames string
+		want    string
+	}{
+		{"named sockets", "api:debug", "api,debug"},
+		{"positional sockets", "", "api,debug"},
+		{"socket unit names", "sales-api:sales-api", "error: "},
+	}
+
+	t.Log("Given the need to accept listeners from systemd.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen passed %s.", testID, test.name)
+			{
+				var files []*os.File
+				for i := 0; i < 2; i++ {
+					ln, err := net.Listen("tcp", "127.0.0.1:0")
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to listen : %s", failed, testID, err)
+					}
+					defer ln.Close()
+
+					f, err := ln.(*net.TCPListener).File()
+					if err != nil {
+						t.Fatalf("\t%s\tTest %d:\tShould be able to get the file : %s", failed, testID, err)
+					}
+					defer f.Close()
+					files = append(files, f)
+				}
+
+				cmd := exec.Command(os.Args[0], "-test.run=^TestInheritedProcess$")
+				cmd.ExtraFiles = files
+				cmd.Env = append(os.Environ(), "HANDOFF_TEST_CHILD=1", "LISTEN_FDS=2")
+				if test.fdNames != "" {
+					cmd.Env = append(cmd.Env, "LISTEN_FDNAMES="+test.fdNames)
+				}
+
+				out, err := cmd.Output()
+				if err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to run the child : %s", failed, testID, err)
+				}
+
+				got := strings.SplitN(string(out), "\n", 2)[0]
+				if !strings.HasPrefix(got, test.want) {
+					t.Fatalf("\t%s\tTest %d:\tShould inherit %q : got %q", failed, testID, test.want, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould inherit %q.", success, testID, got)
+			}
+		}
+	}
+}
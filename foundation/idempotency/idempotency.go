@@ -0,0 +1,130 @@
+// Package idempotency provides storage for the responses of requests made
+// with an idempotency key so retries can be answered without repeating the
+// work.
+package idempotency
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often expired records are discarded.
+const sweepInterval = time.Minute
+
+// Record is what is kept for a key once its request has completed.
+type Record struct {
+	Fingerprint string
+	StatusCode  int
+	Header      http.Header
+	Body        []byte
+}
+
+// Store defines the behavior required to keep records and to serialize
+// requests using the same key. Implementations backed by a shared database
+// allow the guarantees to hold across instances of the service.
+type Store interface {
+
+	// Lock blocks until no other request holds the key or the context is
+	// done. The returned function releases the key.
+	Lock(ctx context.Context, key string) (func(), error)
+
+	// Get returns the record stored for the key, if any.
+	Get(ctx context.Context, key string) (Record, bool, error)
+
+	// Put stores the record for the key for the specified duration.
+	Put(ctx context.Context, key string, rec Record, ttl time.Duration) error
+}
+
+// =============================================================================
+
+// entry is a record held by the memory store.
+type entry struct {
+	rec     Record
+	expires time.Time
+}
+
+// MemoryStore keeps records in memory. Records are not shared between
+// instances of the service and are lost on restart.
+type MemoryStore struct {
+	mu        sync.Mutex
+	entries   map[string]entry
+	locks     map[string]chan struct{}
+	lastSweep time.Time
+}
+
+// NewMemoryStore constructs an empty memory store.
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		entries:   make(map[string]entry),
+		locks:     make(map[string]chan struct{}),
+		lastSweep: time.Now(),
+	}
+}
+
+// Lock implements the Store interface.
+func (ms *MemoryStore) Lock(ctx context.Context, key string) (func(), error) {
+	for {
+		ms.mu.Lock()
+		held, exists := ms.locks[key]
+		if !exists {
+			released := make(chan struct{})
+			ms.locks[key] = released
+			ms.mu.Unlock()
+
+			unlock := func() {
+				ms.mu.Lock()
+				delete(ms.locks, key)
+				ms.mu.Unlock()
+				close(released)
+			}
+			return unlock, nil
+		}
+		ms.mu.Unlock()
+
+		// Wait for the holder to finish and try again.
+		select {
+		case <-held:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+// Get implements the Store interface.
+func (ms *MemoryStore) Get(ctx context.Context, key string) (Record, bool, error) {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	e, exists := ms.entries[key]
+	if !exists || time.Now().After(e.expires) {
+		return Record{}, false, nil
+	}
+
+	return e.rec, true, nil
+}
+
+// Put implements the Store interface. Expired records are discarded
+// periodically as new ones are added.
+func (ms *MemoryStore) Put(ctx context.Context, key string, rec Record, ttl time.Duration) error {
+	ms.mu.Lock()
+	defer ms.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(ms.lastSweep) >= sweepInterval {
+		ms.lastSweep = now
+		for k, e := range ms.entries {
+			if now.After(e.expires) {
+				delete(ms.entries, k)
+			}
+		}
+	}
+
+	ms.entries[key] = entry{
+		rec:     rec,
+		expires: now.Add(ttl),
+	}
+
+	return nil
+}
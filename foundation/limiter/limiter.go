@@ -0,0 +1,182 @@
+// Package limiter bounds the number of requests processed concurrently,
+// queueing a limited number of callers and rejecting the rest.
+package limiter
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+)
+
+// ErrSaturated is returned when both the concurrency limit and the wait
+// queue are exhausted, or a queued caller waited too long.
+var ErrSaturated = errors.New("concurrency limit reached")
+
+// Config defines the behavior of the limiter.
+type Config struct {
+	Limit        int
+	QueueSize    int
+	QueueTimeout time.Duration
+
+	// When Adaptive is set the limit moves between MinLimit and MaxLimit.
+	// It grows while requests complete within TargetLatency and shrinks
+	// when they don't.
+	Adaptive      bool
+	MinLimit      int
+	MaxLimit      int
+	TargetLatency time.Duration
+}
+
+// Limiter tracks requests in flight and the callers waiting for a slot.
+type Limiter struct {
+	cfg Config
+
+	mu        sync.Mutex
+	limit     int
+	inflight  int
+	waiters   []chan struct{}
+	successes int
+	holdoff   int
+}
+
+// New constructs a limiter for the specified configuration.
+func New(cfg Config) *Limiter {
+	if cfg.Limit < 1 {
+		cfg.Limit = 1
+	}
+
+	if cfg.Adaptive {
+		if cfg.MinLimit < 1 {
+			cfg.MinLimit = 1
+		}
+		if cfg.MaxLimit < cfg.MinLimit {
+			cfg.MaxLimit = cfg.MinLimit
+		}
+		cfg.Limit = clamp(cfg.Limit, cfg.MinLimit, cfg.MaxLimit)
+	}
+
+	return &Limiter{
+		cfg:   cfg,
+		limit: cfg.Limit,
+	}
+}
+
+// Limit returns the current concurrency limit.
+func (l *Limiter) Limit() int {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.limit
+}
+
+// Acquire reserves a slot for a request, waiting in the queue if needed.
+// The returned function must be called with the latency of the request
+// once it completes.
+func (l *Limiter) Acquire(ctx context.Context) (func(latency time.Duration), error) {
+	l.mu.Lock()
+
+	if l.inflight < l.limit {
+		l.inflight++
+		l.mu.Unlock()
+		return l.release, nil
+	}
+
+	if len(l.waiters) >= l.cfg.QueueSize {
+		l.mu.Unlock()
+		return nil, ErrSaturated
+	}
+
+	ready := make(chan struct{})
+	l.waiters = append(l.waiters, ready)
+	l.mu.Unlock()
+
+	timer := time.NewTimer(l.cfg.QueueTimeout)
+	defer timer.Stop()
+
+	var err error
+	select {
+	case <-ready:
+		return l.release, nil
+	case <-timer.C:
+		err = ErrSaturated
+	case <-ctx.Done():
+		err = ctx.Err()
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	// The slot may have been handed over while we gave up waiting.
+	// In that case it's ours to use.
+	for i, w := range l.waiters {
+		if w == ready {
+			l.waiters = append(l.waiters[:i], l.waiters[i+1:]...)
+			return nil, err
+		}
+	}
+
+	return l.release, nil
+}
+
+// release returns a slot and hands it to the next caller in the queue.
+func (l *Limiter) release(latency time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.inflight--
+
+	if l.cfg.Adaptive {
+		l.adapt(latency)
+	}
+
+	for l.inflight < l.limit && len(l.waiters) > 0 {
+		ready := l.waiters[0]
+		l.waiters = l.waiters[1:]
+		l.inflight++
+		close(ready)
+	}
+}
+
+// adapt adjusts the limit based on the latency of a completed request. The
+// limit grows by one after a full limit of fast requests and drops by a
+// tenth on a slow one, which backs off quickly and probes slowly. After a
+// drop, the limit holds for a full limit of completions, since requests
+// that were already in flight are slow for the same reason.
+func (l *Limiter) adapt(latency time.Duration) {
+	if l.holdoff > 0 {
+		l.holdoff--
+	}
+
+	if latency > l.cfg.TargetLatency {
+		l.successes = 0
+		if l.holdoff > 0 {
+			return
+		}
+
+		step := l.limit / 10
+		if step < 1 {
+			step = 1
+		}
+
+		l.limit = clamp(l.limit-step, l.cfg.MinLimit, l.cfg.MaxLimit)
+		l.holdoff = l.limit
+		return
+	}
+
+	l.successes++
+	if l.successes >= l.limit {
+		l.successes = 0
+		l.limit = clamp(l.limit+1, l.cfg.MinLimit, l.cfg.MaxLimit)
+	}
+}
+
+func clamp(v, lo, hi int) int {
+	switch {
+	case v < lo:
+		return lo
+	case v > hi:
+		return hi
+	}
+	return v
+}
@@ -0,0 +1,161 @@
+package limiter
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestQueue(t *testing.T) {
+	t.Log("Given the need to queue requests over the concurrency limit.")
+	{
+		l := New(Config{Limit: 1, QueueSize: 1, QueueTimeout: time.Minute})
+
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the limit and the queue are full.", testID)
+		{
+			release, err := l.Acquire(context.Background())
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould get the first slot : %s", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould get the first slot.", success, testID)
+
+			queued := make(chan error, 1)
+			go func() {
+				release, err := l.Acquire(context.Background())
+				if err == nil {
+					release(0)
+				}
+				queued <- err
+			}()
+
+			// Wait for the second caller to join the queue.
+			for {
+				l.mu.Lock()
+				n := len(l.waiters)
+				l.mu.Unlock()
+				if n == 1 {
+					break
+				}
+				time.Sleep(time.Millisecond)
+			}
+
+			if _, err := l.Acquire(context.Background()); !errors.Is(err, ErrSaturated) {
+				t.Fatalf("\t%s\tTest %d:\tShould reject the caller over the queue : got %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould reject the caller over the queue.", success, testID)
+
+			release(0)
+			if err := <-queued; err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould hand the slot to the queued caller : %s", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould hand the slot to the queued caller.", success, testID)
+
+			if l.inflight != 0 || len(l.waiters) != 0 {
+				t.Fatalf("\t%s\tTest %d:\tShould have nothing in flight : got %d in flight, %d waiting", failed, testID, l.inflight, len(l.waiters))
+			}
+			t.Logf("\t%s\tTest %d:\tShould have nothing in flight.", success, testID)
+		}
+	}
+}
+
+func TestQueueGiveUp(t *testing.T) {
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	tt := []struct {
+		name string
+		ctx  context.Context
+		err  error
+	}{
+		{"the queue timeout passes", context.Background(), ErrSaturated},
+		{"the caller goes away", canceled, context.Canceled},
+	}
+
+	t.Log("Given the need to stop waiting for a slot.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen %s.", testID, test.name)
+			{
+				l := New(Config{Limit: 1, QueueSize: 1, QueueTimeout: 10 * time.Millisecond})
+				if _, err := l.Acquire(context.Background()); err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould get the first slot : %s", failed, testID, err)
+				}
+
+				if _, err := l.Acquire(test.ctx); !errors.Is(err, test.err) {
+					t.Fatalf("\t%s\tTest %d:\tShould report %v : got %v", failed, testID, test.err, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould report %v.", success, testID, test.err)
+
+				if len(l.waiters) != 0 {
+					t.Fatalf("\t%s\tTest %d:\tShould leave the queue : got %d waiting", failed, testID, len(l.waiters))
+				}
+				t.Logf("\t%s\tTest %d:\tShould leave the queue.", success, testID)
+			}
+		}
+	}
+}
+
+func TestAdapt(t *testing.T) {
+	const (
+		fast = 10 * time.Millisecond
+		slow = time.Second
+	)
+
+	repeat := func(latency time.Duration, n int) []time.Duration {
+		out := make([]time.Duration, n)
+		for i := range out {
+			out[i] = latency
+		}
+		return out
+	}
+
+	tt := []struct {
+		name      string
+		limit     int
+		latencies []time.Duration
+		want      int
+	}{
+		{"grows after a full limit of fast requests", 4, repeat(fast, 4), 5},
+		{"holds short of a full limit", 4, repeat(fast, 3), 4},
+		{"drops by one when small", 4, []time.Duration{slow}, 3},
+		{"drops by a tenth when large", 50, []time.Duration{slow}, 45},
+		{"stops at the minimum", 4, repeat(slow, 9), 2},
+		{"drops once for a burst of slow requests", 100, repeat(slow, 30), 90},
+		{"drops again after a full limit of completions", 100, repeat(slow, 91), 81},
+		{"stops at the maximum", 99, repeat(fast, 200), 100},
+		{"starts over after a slow request", 4, append(repeat(fast, 3), slow, fast, fast), 3},
+	}
+
+	t.Log("Given the need to adapt the limit to the latency of requests.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen the limit %s.", testID, test.name)
+			{
+				l := New(Config{
+					Limit:         test.limit,
+					Adaptive:      true,
+					MinLimit:      2,
+					MaxLimit:      100,
+					TargetLatency: 250 * time.Millisecond,
+				})
+
+				for _, latency := range test.latencies {
+					l.adapt(latency)
+				}
+
+				if l.Limit() != test.want {
+					t.Fatalf("\t%s\tTest %d:\tShould have a limit of %d : got %d", failed, testID, test.want, l.Limit())
+				}
+				t.Logf("\t%s\tTest %d:\tShould have a limit of %d.", success, testID, test.want)
+			}
+		}
+	}
+}
@@ -0,0 +1,362 @@
+// Package openapi generates OpenAPI 3 documents from the routes registered
+// with the web framework.
+package openapi
+
+import (
+	"net/http"
+	"path"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/Joggz/services/foundation/web"
+)
+
+// Version is the version of the OpenAPI specification documents conform to.
+const Version = "3.0.3"
+
+// Document represents the root of an OpenAPI document.
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components *Components         `json:"components,omitempty"`
+}
+
+// Info provides metadata about the api.
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem describes the operations available on a single path keyed by
+// the lower case http method.
+type PathItem map[string]Operation
+
+// Operation describes a single api operation on a path.
+type Operation struct {
+	Summary     string              `json:"summary,omitempty"`
+	OperationID string              `json:"operationId"`
+	Parameters  []Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody        `json:"requestBody,omitempty"`
+	Responses   map[string]Response `json:"responses"`
+}
+
+// Parameter describes a single operation parameter.
+type Parameter struct {
+	Name     string  `json:"name"`
+	In       string  `json:"in"`
+	Required bool    `json:"required"`
+	Schema   *Schema `json:"schema"`
+}
+
+// RequestBody describes a single request body.
+type RequestBody struct {
+	Required bool                 `json:"required"`
+	Content  map[string]MediaType `json:"content"`
+}
+
+// Response describes a single response from an api operation.
+type Response struct {
+	Description string               `json:"description"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+// MediaType provides the schema for a media type.
+type MediaType struct {
+	Schema *Schema `json:"schema"`
+}
+
+// Components holds the reusable schemas referenced by the document.
+type Components struct {
+	Schemas map[string]*Schema `json:"schemas,omitempty"`
+}
+
+// Schema describes a data type.
+type Schema struct {
+	Ref                  string             `json:"$ref,omitempty"`
+	Type                 string             `json:"type,omitempty"`
+	Format               string             `json:"format,omitempty"`
+	Items                *Schema            `json:"items,omitempty"`
+	Properties           map[string]*Schema `json:"properties,omitempty"`
+	AdditionalProperties *Schema            `json:"additionalProperties,omitempty"`
+	Required             []string           `json:"required,omitempty"`
+}
+
+// =============================================================================
+
+// Config describes the document to generate.
+type Config struct {
+	Title       string
+	Description string
+	Version     string
+
+	// Error is a value of the type the api responds with on failure. It is
+	// documented as the default response of every operation when set.
+	Error any
+}
+
+// Generate constructs the document describing the specified endpoints.
+func Generate(cfg Config, endpoints []web.Endpoint) Document {
+	g := generator{
+		schemas: make(map[string]*Schema),
+	}
+
+	doc := Document{
+		OpenAPI: Version,
+		Info: Info{
+			Title:       cfg.Title,
+			Description: cfg.Description,
+			Version:     cfg.Version,
+		},
+		Paths: make(map[string]PathItem),
+	}
+
+	for _, ep := range endpoints {
+		p, params := convertPath(ep.Path)
+
+		op := Operation{
+			Summary:     ep.Doc.Summary,
+			OperationID: operationID(ep.Method, ep.Path),
+			Parameters:  params,
+			Responses:   make(map[string]Response),
+		}
+
+		if ep.Doc.Request != nil {
+			op.RequestBody = &RequestBody{
+				Required: true,
+				Content:  g.content(ep.Doc.Request),
+			}
+		}
+
+		status := ep.Doc.Status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		resp := Response{
+			Description: http.StatusText(status),
+		}
+		if ep.Doc.Response != nil && status != http.StatusNoContent {
+			resp.Content = g.content(ep.Doc.Response)
+		}
+		op.Responses[strconv.Itoa(status)] = resp
+
+		if cfg.Error != nil {
+			op.Responses["default"] = Response{
+				Description: "Error",
+				Content:     g.content(cfg.Error),
+			}
+		}
+
+		item, ok := doc.Paths[p]
+		if !ok {
+			item = make(PathItem)
+			doc.Paths[p] = item
+		}
+		item[strings.ToLower(ep.Method)] = op
+	}
+
+	if len(g.schemas) > 0 {
+		doc.Components = &Components{
+			Schemas: g.schemas,
+		}
+	}
+
+	return doc
+}
+
+// convertPath converts a httptreemux path into an OpenAPI path template
+// and returns the path parameters it declares.
+func convertPath(p string) (string, []Parameter) {
+	var params []Parameter
+
+	segments := strings.Split(p, "/")
+	for i, seg := range segments {
+		if len(seg) < 2 || (seg[0] != ':' && seg[0] != '*') {
+			continue
+		}
+
+		name := seg[1:]
+		segments[i] = "{" + name + "}"
+		params = append(params, Parameter{
+			Name:     name,
+			In:       "path",
+			Required: true,
+			Schema:   &Schema{Type: "string"},
+		})
+	}
+
+	return strings.Join(segments, "/"), params
+}
+
+// operationID constructs a unique identifier for the operation from the
+// method and path, such as "get_v1_users_id".
+func operationID(method string, p string) string {
+	var b strings.Builder
+	b.WriteString(strings.ToLower(method))
+
+	for _, seg := range strings.Split(path.Clean(p), "/") {
+		seg = strings.TrimLeft(seg, ":*")
+		if seg == "" {
+			continue
+		}
+		b.WriteByte('_')
+		b.WriteString(seg)
+	}
+
+	return b.String()
+}
+
+// =============================================================================
+
+// generator builds schemas for Go types, registering named struct types
+// as components so they are only described once.
+type generator struct {
+	schemas map[string]*Schema
+}
+
+var timeType = reflect.TypeOf(time.Time{})
+
+// content returns the media type content for the specified value.
+func (g *generator) content(v any) map[string]MediaType {
+	return map[string]MediaType{
+		web.MediaTypeJSON: {Schema: g.schema(reflect.TypeOf(v))},
+	}
+}
+
+// schema returns the schema for the specified type.
+func (g *generator) schema(t reflect.Type) *Schema {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+
+	if t == timeType {
+		return &Schema{Type: "string", Format: "date-time"}
+	}
+
+	switch t.Kind() {
+	case reflect.Bool:
+		return &Schema{Type: "boolean"}
+
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32:
+		return &Schema{Type: "integer", Format: "int32"}
+
+	case reflect.Int64, reflect.Uint64:
+		return &Schema{Type: "integer", Format: "int64"}
+
+	case reflect.Float32:
+		return &Schema{Type: "number", Format: "float"}
+
+	case reflect.Float64:
+		return &Schema{Type: "number", Format: "double"}
+
+	case reflect.String:
+		return &Schema{Type: "string"}
+
+	case reflect.Slice, reflect.Array:
+		if t.Elem().Kind() == reflect.Uint8 {
+			return &Schema{Type: "string", Format: "byte"}
+		}
+		return &Schema{Type: "array", Items: g.schema(t.Elem())}
+
+	case reflect.Map:
+		return &Schema{Type: "object", AdditionalProperties: g.schema(t.Elem())}
+
+	case reflect.Struct:
+		if t.Name() == "" {
+			return g.object(t)
+		}
+
+		name := schemaName(t)
+		if _, exists := g.schemas[name]; !exists {
+
+			// Register the name before describing the fields so recursive
+			// types reference themselves instead of looping forever.
+			g.schemas[name] = &Schema{}
+			*g.schemas[name] = *g.object(t)
+		}
+		return &Schema{Ref: "#/components/schemas/" + name}
+	}
+
+	// Interfaces and anything else we can't describe accept any value.
+	return &Schema{}
+}
+
+// object describes the fields of a struct type as they are encoded by
+// the encoding/json package.
+func (g *generator) object(t reflect.Type) *Schema {
+	s := Schema{
+		Type:       "object",
+		Properties: make(map[string]*Schema),
+	}
+
+	for i := 0; i < t.NumField(); i++ {
+		fld := t.Field(i)
+
+		// Embedded structs have their exported fields promoted even when
+		// the struct type itself is unexported.
+		if !fld.IsExported() && !(fld.Anonymous && isStruct(fld.Type)) {
+			continue
+		}
+
+		tag := fld.Tag.Get("json")
+		if tag == "-" {
+			continue
+		}
+		name := strings.Split(tag, ",")[0]
+
+		// Embedded structs without a name have their fields promoted.
+		if fld.Anonymous && name == "" && isStruct(fld.Type) {
+			ft := fld.Type
+			if ft.Kind() == reflect.Pointer {
+				ft = ft.Elem()
+			}
+
+			embedded := g.object(ft)
+			for k, v := range embedded.Properties {
+				s.Properties[k] = v
+			}
+			s.Required = append(s.Required, embedded.Required...)
+			continue
+		}
+
+		if name == "" {
+			name = fld.Name
+		}
+
+		s.Properties[name] = g.schema(fld.Type)
+		if isRequired(fld) {
+			s.Required = append(s.Required, name)
+		}
+	}
+
+	return &s
+}
+
+// isStruct reports if the type is a struct or a pointer to one.
+func isStruct(t reflect.Type) bool {
+	if t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
+
+// isRequired reports if the field is declared as required through its
+// validate tag.
+func isRequired(fld reflect.StructField) bool {
+	for _, rule := range strings.Split(fld.Tag.Get("validate"), ",") {
+		if rule == "required" {
+			return true
+		}
+	}
+	return false
+}
+
+// schemaName returns the component name for a named type, qualified by
+// its package name to avoid collisions, such as "testgrp.Status".
+func schemaName(t reflect.Type) string {
+	return path.Base(t.PkgPath()) + "." + t.Name()
+}
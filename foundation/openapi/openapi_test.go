@@ -0,0 +1,200 @@
+package openapi_test
+
+import (
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/Joggz/services/foundation/openapi"
+	"github.com/Joggz/services/foundation/web"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// Audit is embedded to have its fields promoted.
+type Audit struct {
+	Created time.Time `json:"created"`
+	By      string    `json:"by" validate:"required"`
+}
+
+// revision is embedded to have its fields promoted even though the type
+// itself is unexported.
+type revision struct {
+	Revision int `json:"revision"`
+}
+
+// Part is referenced by Product.
+type Part struct {
+	Name string `json:"name"`
+}
+
+// Product exercises the kinds of fields the generator describes.
+type Product struct {
+	Audit
+	revision
+	ID     string           `json:"id" validate:"required"`
+	Name   string           `json:"name" validate:"required,min=3"`
+	Note   string           `json:"note,omitempty"`
+	Cost   float64          `json:",omitempty"`
+	Skip   string           `json:"-"`
+	Tags   []string         `json:"tags"`
+	Image  []byte           `json:"image"`
+	Attrs  map[string]int64 `json:"attrs"`
+	Parts  []Part           `json:"parts"`
+	Extra  any              `json:"extra"`
+	secret string
+}
+
+// Node is a recursive type.
+type Node struct {
+	Name     string `json:"name"`
+	Parent   *Node  `json:"parent"`
+	Children []Node `json:"children"`
+}
+
+func TestPaths(t *testing.T) {
+	endpoints := []web.Endpoint{
+		{Method: http.MethodGet, Path: "/v1/products/:id"},
+		{Method: http.MethodPut, Path: "/v1/products/:id"},
+		{Method: http.MethodPost, Path: "/v1/products", Doc: web.Doc{Request: Part{}, Response: Part{}, Status: http.StatusCreated}},
+		{Method: http.MethodDelete, Path: "/v1/products/:id/parts/:name", Doc: web.Doc{Response: Part{}, Status: http.StatusNoContent}},
+		{Method: http.MethodGet, Path: "/v1/files/*path"},
+	}
+
+	doc := openapi.Generate(openapi.Config{Title: "test", Version: "1"}, endpoints)
+
+	param := func(name string) openapi.Parameter {
+		return openapi.Parameter{Name: name, In: "path", Required: true, Schema: &openapi.Schema{Type: "string"}}
+	}
+
+	tt := []struct {
+		path        string
+		method      string
+		operationID string
+		params      []openapi.Parameter
+		status      string
+		content     bool
+	}{
+		{"/v1/products/{id}", "get", "get_v1_products_id", []openapi.Parameter{param("id")}, "200", false},
+		{"/v1/products/{id}", "put", "put_v1_products_id", []openapi.Parameter{param("id")}, "200", false},
+		{"/v1/products", "post", "post_v1_products", nil, "201", true},
+		{"/v1/products/{id}/parts/{name}", "delete", "delete_v1_products_id_parts_name", []openapi.Parameter{param("id"), param("name")}, "204", false},
+		{"/v1/files/{path}", "get", "get_v1_files_path", []openapi.Parameter{param("path")}, "200", false},
+	}
+
+	t.Log("Given the need to describe the paths of registered routes.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen describing %s %s.", testID, test.method, test.path)
+			{
+				op, exists := doc.Paths[test.path][test.method]
+				if !exists {
+					t.Fatalf("\t%s\tTest %d:\tShould have the operation : got %v", failed, testID, doc.Paths)
+				}
+				t.Logf("\t%s\tTest %d:\tShould have the operation.", success, testID)
+
+				if op.OperationID != test.operationID {
+					t.Fatalf("\t%s\tTest %d:\tShould have operation id %q : got %q", failed, testID, test.operationID, op.OperationID)
+				}
+				t.Logf("\t%s\tTest %d:\tShould have operation id %q.", success, testID, test.operationID)
+
+				if !reflect.DeepEqual(op.Parameters, test.params) {
+					t.Fatalf("\t%s\tTest %d:\tShould declare the path parameters : got %+v", failed, testID, op.Parameters)
+				}
+				t.Logf("\t%s\tTest %d:\tShould declare the path parameters.", success, testID)
+
+				resp, exists := op.Responses[test.status]
+				if !exists || (resp.Content != nil) != test.content {
+					t.Fatalf("\t%s\tTest %d:\tShould respond with %s and content %v : got %+v", failed, testID, test.status, test.content, op.Responses)
+				}
+				t.Logf("\t%s\tTest %d:\tShould respond with %s and content %v.", success, testID, test.status, test.content)
+			}
+		}
+	}
+}
+
+func TestSchemas(t *testing.T) {
+	ref := func(name string) *openapi.Schema {
+		return &openapi.Schema{Ref: "#/components/schemas/openapi_test." + name}
+	}
+
+	tt := []struct {
+		name  string
+		value any
+		want  map[string]*openapi.Schema
+	}{
+		{
+			name:  "embedded, omitempty, slice and map fields",
+			value: Product{},
+			want: map[string]*openapi.Schema{
+				"openapi_test.Product": {
+					Type: "object",
+					Properties: map[string]*openapi.Schema{
+						"created":  {Type: "string", Format: "date-time"},
+						"by":       {Type: "string"},
+						"revision": {Type: "integer", Format: "int32"},
+						"id":       {Type: "string"},
+						"name":     {Type: "string"},
+						"note":     {Type: "string"},
+						"Cost":     {Type: "number", Format: "double"},
+						"tags":     {Type: "array", Items: &openapi.Schema{Type: "string"}},
+						"image":    {Type: "string", Format: "byte"},
+						"attrs":    {Type: "object", AdditionalProperties: &openapi.Schema{Type: "integer", Format: "int64"}},
+						"parts":    {Type: "array", Items: ref("Part")},
+						"extra":    {},
+					},
+					Required: []string{"by", "id", "name"},
+				},
+				"openapi_test.Part": {
+					Type: "object",
+					Properties: map[string]*openapi.Schema{
+						"name": {Type: "string"},
+					},
+				},
+			},
+		},
+		{
+			name:  "a recursive type",
+			value: &Node{},
+			want: map[string]*openapi.Schema{
+				"openapi_test.Node": {
+					Type: "object",
+					Properties: map[string]*openapi.Schema{
+						"name":     {Type: "string"},
+						"parent":   ref("Node"),
+						"children": {Type: "array", Items: ref("Node")},
+					},
+				},
+			},
+		},
+	}
+
+	t.Log("Given the need to describe the Go types of requests and responses.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen describing %s.", testID, test.name)
+			{
+				endpoints := []web.Endpoint{
+					{Method: http.MethodGet, Path: "/test", Doc: web.Doc{Response: test.value}},
+				}
+				doc := openapi.Generate(openapi.Config{Title: "test", Version: "1"}, endpoints)
+
+				if doc.Components == nil || !reflect.DeepEqual(doc.Components.Schemas, test.want) {
+					t.Fatalf("\t%s\tTest %d:\tShould describe the type : got %+v", failed, testID, doc.Components)
+				}
+				t.Logf("\t%s\tTest %d:\tShould describe the type.", success, testID)
+
+				schema := doc.Paths["/test"]["get"].Responses["200"].Content[web.MediaTypeJSON].Schema
+				if schema.Ref == "" {
+					t.Fatalf("\t%s\tTest %d:\tShould reference the component : got %+v", failed, testID, schema)
+				}
+				t.Logf("\t%s\tTest %d:\tShould reference the component.", success, testID)
+			}
+		}
+	}
+}
@@ -0,0 +1,118 @@
+// Package ratelimit provides a token bucket rate limiter that tracks a
+// separate bucket for every client key.
+package ratelimit
+
+import (
+	"math"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often buckets that have refilled are discarded.
+const sweepInterval = time.Minute
+
+// Result describes the outcome of taking a token from a bucket.
+type Result struct {
+	Allowed    bool
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
+// bucket holds the tokens available to a single key.
+type bucket struct {
+	tokens float64
+	last   time.Time
+}
+
+// Limiter allows up to burst requests per key at once, refilling tokens at
+// rate per second.
+type Limiter struct {
+	rate  float64
+	burst int
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+// New constructs a limiter refilling rate tokens per second up to burst.
+func New(rate float64, burst int) *Limiter {
+	if burst < 1 {
+		burst = 1
+	}
+
+	return &Limiter{
+		rate:      rate,
+		burst:     burst,
+		buckets:   make(map[string]*bucket),
+		lastSweep: time.Now(),
+	}
+}
+
+// Allow takes a token from the bucket for the specified key.
+func (l *Limiter) Allow(key string) Result {
+	return l.allowAt(key, time.Now())
+}
+
+// allowAt takes a token from the bucket for the specified key at the
+// specified time.
+func (l *Limiter) allowAt(key string, now time.Time) Result {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.sweep(now)
+
+	b, exists := l.buckets[key]
+	if !exists {
+		b = &bucket{
+			tokens: float64(l.burst),
+			last:   now,
+		}
+		l.buckets[key] = b
+	}
+
+	// Refill the tokens earned since the bucket was last used.
+	b.tokens = math.Min(float64(l.burst), b.tokens+now.Sub(b.last).Seconds()*l.rate)
+	b.last = now
+
+	res := Result{
+		Limit: l.burst,
+	}
+
+	if b.tokens >= 1 {
+		b.tokens--
+		res.Allowed = true
+	} else {
+		res.RetryAfter = l.duration(1 - b.tokens)
+	}
+
+	res.Remaining = int(b.tokens)
+	res.Reset = l.duration(float64(l.burst) - b.tokens)
+
+	return res
+}
+
+// sweep discards the buckets that would be full by now, since a new
+// bucket behaves the same. This keeps memory bounded by active clients.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < sweepInterval {
+		return
+	}
+	l.lastSweep = now
+
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*l.rate >= float64(l.burst) {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// duration returns how long it takes to earn the specified tokens.
+func (l *Limiter) duration(tokens float64) time.Duration {
+	if l.rate <= 0 {
+		return time.Duration(math.MaxInt64)
+	}
+	return time.Duration(tokens / l.rate * float64(time.Second))
+}
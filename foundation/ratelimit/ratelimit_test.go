@@ -0,0 +1,113 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestAllow(t *testing.T) {
+	type step struct {
+		key        string
+		after      time.Duration
+		allowed    bool
+		remaining  int
+		retryAfter time.Duration
+	}
+
+	tt := []struct {
+		name  string
+		rate  float64
+		burst int
+		steps []step
+	}{
+		{
+			name:  "a burst then a refill",
+			rate:  1,
+			burst: 2,
+			steps: []step{
+				{"a", 0, true, 1, 0},
+				{"a", 0, true, 0, 0},
+				{"a", 0, false, 0, time.Second},
+				{"a", 500 * time.Millisecond, false, 0, 500 * time.Millisecond},
+				{"a", 500 * time.Millisecond, true, 0, 0},
+			},
+		},
+		{
+			name:  "separate keys",
+			rate:  1,
+			burst: 1,
+			steps: []step{
+				{"a", 0, true, 0, 0},
+				{"a", 0, false, 0, time.Second},
+				{"b", 0, true, 0, 0},
+			},
+		},
+		{
+			name:  "a full refill",
+			rate:  10,
+			burst: 3,
+			steps: []step{
+				{"a", 0, true, 2, 0},
+				{"a", 0, true, 1, 0},
+				{"a", time.Hour, true, 2, 0},
+			},
+		},
+	}
+
+	t.Log("Given the need to limit the rate of requests per key.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen taking tokens for %s.", testID, test.name)
+			{
+				l := New(test.rate, test.burst)
+				now := time.Date(2022, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+				for i, s := range test.steps {
+					now = now.Add(s.after)
+					res := l.allowAt(s.key, now)
+
+					if res.Allowed != s.allowed || res.Remaining != s.remaining || res.RetryAfter != s.retryAfter {
+						t.Fatalf("\t%s\tTest %d:\tShould get allowed %v, remaining %d, retry after %v at step %d : got %+v",
+							failed, testID, s.allowed, s.remaining, s.retryAfter, i, res)
+					}
+					if res.Limit != test.burst {
+						t.Fatalf("\t%s\tTest %d:\tShould report a limit of %d at step %d : got %d", failed, testID, test.burst, i, res.Limit)
+					}
+				}
+				t.Logf("\t%s\tTest %d:\tShould allow and refuse in order.", success, testID)
+			}
+		}
+	}
+}
+
+func TestSweep(t *testing.T) {
+	t.Log("Given the need to bound memory by the active clients.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen buckets have refilled by the next sweep.", testID)
+		{
+			l := New(1, 1)
+			now := l.lastSweep
+
+			l.allowAt("idle", now)
+			l.allowAt("busy", now.Add(sweepInterval-time.Millisecond))
+			l.allowAt("busy", now.Add(sweepInterval))
+
+			if _, exists := l.buckets["idle"]; exists {
+				t.Fatalf("\t%s\tTest %d:\tShould discard the refilled bucket.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould discard the refilled bucket.", success, testID)
+
+			if _, exists := l.buckets["busy"]; !exists {
+				t.Fatalf("\t%s\tTest %d:\tShould keep the active bucket.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep the active bucket.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+// Package tlsconfig constructs the TLS configuration for serving HTTPS,
+// reloading the server certificate when it changes on disk.
+package tlsconfig
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"sync/atomic"
+	"time"
+)
+
+// Config defines how TLS is served.
+type Config struct {
+	CertFile string
+	KeyFile  string
+
+	// ClientCAFile enables mutual TLS when set. Clients must present a
+	// certificate signed by one of the authorities in the file.
+	ClientCAFile string
+
+	// MinVersion is "1.2" or "1.3".
+	MinVersion string
+
+	// CipherPolicy is "default" to use the Go defaults or "strict" to only
+	// allow forward secret AEAD cipher suites for TLS 1.2.
+	CipherPolicy string
+
+	// ReloadInterval is how often the certificate files are checked for
+	// changes. Zero disables reloading.
+	ReloadInterval time.Duration
+
+	// OnReload is called after every attempt to reload the certificate.
+	OnReload func(err error)
+
+	// Done stops checking for changes when closed. When nil the checks
+	// run for the life of the process.
+	Done <-chan struct{}
+}
+
+// strictCipherSuites are the TLS 1.2 suites allowed by the strict policy.
+// TLS 1.3 suites are not configurable and are always secure.
+var strictCipherSuites = []uint16{
+	tls.TLS_ECDHE_ECDSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256,
+	tls.TLS_ECDHE_ECDSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_RSA_WITH_AES_256_GCM_SHA384,
+	tls.TLS_ECDHE_ECDSA_WITH_CHACHA20_POLY1305_SHA256,
+	tls.TLS_ECDHE_RSA_WITH_CHACHA20_POLY1305_SHA256,
+}
+
+// New constructs the TLS configuration for the specified settings. The
+// certificate is loaded right away so problems are reported at startup.
+func New(cfg Config) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, errors.New("certificate and key files are required")
+	}
+
+	tlsCfg := tls.Config{
+		NextProtos: []string{"h2", "http/1.1"},
+	}
+
+	switch cfg.MinVersion {
+	case "", "1.2":
+		tlsCfg.MinVersion = tls.VersionTLS12
+	case "1.3":
+		tlsCfg.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported minimum tls version %q", cfg.MinVersion)
+	}
+
+	switch cfg.CipherPolicy {
+	case "", "default":
+	case "strict":
+		tlsCfg.CipherSuites = strictCipherSuites
+	default:
+		return nil, fmt.Errorf("unknown cipher policy %q", cfg.CipherPolicy)
+	}
+
+	rl := reloader{
+		certFile: cfg.CertFile,
+		keyFile:  cfg.KeyFile,
+		onReload: cfg.OnReload,
+	}
+	if err := rl.load(); err != nil {
+		return nil, err
+	}
+	tlsCfg.GetCertificate = rl.getCertificate
+
+	if cfg.ClientCAFile != "" {
+		pem, err := os.ReadFile(cfg.ClientCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading client ca file: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, errors.New("client ca file contains no certificates")
+		}
+
+		tlsCfg.ClientCAs = pool
+		tlsCfg.ClientAuth = tls.RequireAndVerifyClientCert
+	}
+
+	if cfg.ReloadInterval > 0 {
+		go rl.run(cfg.ReloadInterval, cfg.Done)
+	}
+
+	return &tlsCfg, nil
+}
+
+// =============================================================================
+
+// reloader serves the certificate from disk, loading it again when the
+// files change. Kubernetes updates mounted secrets by swapping a symlink,
+// which shows up as a new modification time. The files are checked in the
+// background so handshakes never wait on the disk.
+type reloader struct {
+	certFile string
+	keyFile  string
+	onReload func(err error)
+
+	// cert holds the *tls.Certificate being served.
+	cert atomic.Value
+
+	// modTime is only used by load, which runs from New and then from
+	// the reload goroutine alone.
+	modTime time.Time
+}
+
+// getCertificate implements the tls.Config GetCertificate callback.
+func (rl *reloader) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return rl.cert.Load().(*tls.Certificate), nil
+}
+
+// run checks the files for changes every interval until done is closed.
+func (rl *reloader) run(interval time.Duration, done <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			rl.reload()
+		case <-done:
+			return
+		}
+	}
+}
+
+// reload loads the certificate again if the files changed. On failure we
+// keep serving the certificate we already have.
+func (rl *reloader) reload() {
+	modTime, err := rl.latestModTime()
+	if err != nil || modTime.Equal(rl.modTime) {
+		return
+	}
+
+	err = rl.load()
+	if rl.onReload != nil {
+		rl.onReload(err)
+	}
+}
+
+// load reads the certificate from disk.
+func (rl *reloader) load() error {
+	modTime, err := rl.latestModTime()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(rl.certFile, rl.keyFile)
+	if err != nil {
+		return fmt.Errorf("loading key pair: %w", err)
+	}
+
+	rl.cert.Store(&cert)
+	rl.modTime = modTime
+
+	return nil
+}
+
+// latestModTime returns the most recent modification time of the files.
+func (rl *reloader) latestModTime() (time.Time, error) {
+	var latest time.Time
+
+	for _, name := range []string{rl.certFile, rl.keyFile} {
+		fi, err := os.Stat(name)
+		if err != nil {
+			return time.Time{}, fmt.Errorf("stat %s: %w", name, err)
+		}
+		if fi.ModTime().After(latest) {
+			latest = fi.ModTime()
+		}
+	}
+
+	return latest, nil
+}
@@ -0,0 +1,299 @@
+package tlsconfig_test
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/Joggz/services/foundation/tlsconfig"
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+// authority signs the certificates used by the tests.
+type authority struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+}
+
+// newAuthority constructs a self signed certificate authority.
+func newAuthority(t *testing.T) authority {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating ca key : %s", err)
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		KeyUsage:              x509.KeyUsageCertSign,
+		BasicConstraintsValid: true,
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, &tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("creating ca certificate : %s", err)
+	}
+
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parsing ca certificate : %s", err)
+	}
+
+	return authority{cert: cert, key: key}
+}
+
+// issue signs a certificate for the common name and returns it in PEM form
+// along with its key.
+func (ca authority) issue(t *testing.T, cn string, usage x509.ExtKeyUsage) (certPEM []byte, keyPEM []byte) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generating key : %s", err)
+	}
+
+	tmpl := x509.Certificate{
+		SerialNumber: big.NewInt(time.Now().UnixNano()),
+		Subject:      pkix.Name{CommonName: cn},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{usage},
+		IPAddresses:  []net.IP{net.ParseIP("127.0.0.1")},
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, &tmpl, ca.cert, &key.PublicKey, ca.key)
+	if err != nil {
+		t.Fatalf("creating certificate : %s", err)
+	}
+
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshaling key : %s", err)
+	}
+
+	certPEM = pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM = pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER})
+
+	return certPEM, keyPEM
+}
+
+// writeFile writes the file with the specified modification time, so the
+// test controls when a change is seen no matter the resolution of the clock.
+func writeFile(t *testing.T, name string, data []byte, mod time.Time) {
+	if err := os.WriteFile(name, data, 0600); err != nil {
+		t.Fatalf("writing %s : %s", name, err)
+	}
+	if err := os.Chtimes(name, mod, mod); err != nil {
+		t.Fatalf("touching %s : %s", name, err)
+	}
+}
+
+// commonName returns the common name of the certificate being served.
+func commonName(t *testing.T, tlsCfg *tls.Config) string {
+	cert, err := tlsCfg.GetCertificate(&tls.ClientHelloInfo{})
+	if err != nil {
+		t.Fatalf("getting certificate : %s", err)
+	}
+
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		t.Fatalf("parsing certificate : %s", err)
+	}
+
+	return leaf.Subject.CommonName
+}
+
+func TestReload(t *testing.T) {
+	ca := newAuthority(t)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+
+	base := time.Now().Truncate(time.Second)
+
+	certPEM, keyPEM := ca.issue(t, "first", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM, base)
+	writeFile(t, keyFile, keyPEM, base)
+
+	done := make(chan struct{})
+	defer close(done)
+
+	reloads := make(chan error)
+	tlsCfg, err := tlsconfig.New(tlsconfig.Config{
+		CertFile:       certFile,
+		KeyFile:        keyFile,
+		ReloadInterval: 10 * time.Millisecond,
+		OnReload: func(err error) {
+			select {
+			case reloads <- err:
+			case <-done:
+			}
+		},
+		Done: done,
+	})
+	if err != nil {
+		t.Fatalf("constructing tls config : %s", err)
+	}
+
+	// wait returns the error of the first reload attempt that succeeds or
+	// fails as expected. The files aren't replaced atomically, so a reload
+	// can see the new key with the old certificate along the way.
+	wait := func(fail bool) error {
+		timeout := time.After(5 * time.Second)
+		for {
+			select {
+			case err := <-reloads:
+				if (err != nil) == fail {
+					return err
+				}
+			case <-timeout:
+				t.Fatal("timed out waiting for a reload")
+				return nil
+			}
+		}
+	}
+
+	t.Log("Given the need to reload the certificate when it changes on disk.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen the certificate is replaced.", testID)
+		{
+			certPEM, keyPEM := ca.issue(t, "second", x509.ExtKeyUsageServerAuth)
+			writeFile(t, keyFile, keyPEM, base.Add(time.Hour))
+			writeFile(t, certFile, certPEM, base.Add(time.Hour))
+
+			if err := wait(false); err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to reload the certificate : %s", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould be able to reload the certificate.", success, testID)
+
+			if cn := commonName(t, tlsCfg); cn != "second" {
+				t.Fatalf("\t%s\tTest %d:\tShould serve the new certificate : got %q", failed, testID, cn)
+			}
+			t.Logf("\t%s\tTest %d:\tShould serve the new certificate.", success, testID)
+		}
+
+		testID++
+		t.Logf("\tTest %d:\tWhen the certificate is replaced with garbage.", testID)
+		{
+			writeFile(t, certFile, []byte("garbage"), base.Add(2*time.Hour))
+
+			if err := wait(true); err == nil {
+				t.Fatalf("\t%s\tTest %d:\tShould fail to reload the certificate.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould fail to reload the certificate.", success, testID)
+
+			if cn := commonName(t, tlsCfg); cn != "second" {
+				t.Fatalf("\t%s\tTest %d:\tShould keep serving the old certificate : got %q", failed, testID, cn)
+			}
+			t.Logf("\t%s\tTest %d:\tShould keep serving the old certificate.", success, testID)
+		}
+	}
+}
+
+func TestClientSubject(t *testing.T) {
+	ca := newAuthority(t)
+
+	dir := t.TempDir()
+	certFile := filepath.Join(dir, "tls.crt")
+	keyFile := filepath.Join(dir, "tls.key")
+	caFile := filepath.Join(dir, "ca.crt")
+
+	certPEM, keyPEM := ca.issue(t, "server", x509.ExtKeyUsageServerAuth)
+	writeFile(t, certFile, certPEM, time.Now())
+	writeFile(t, keyFile, keyPEM, time.Now())
+	writeFile(t, caFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: ca.cert.Raw}), time.Now())
+
+	tlsCfg, err := tlsconfig.New(tlsconfig.Config{
+		CertFile:     certFile,
+		KeyFile:      keyFile,
+		ClientCAFile: caFile,
+	})
+	if err != nil {
+		t.Fatalf("constructing tls config : %s", err)
+	}
+
+	app := web.NewApp(make(chan os.Signal, 1), zap.NewNop().Sugar())
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		_, err := io.WriteString(w, web.GetClientSubject(ctx))
+		return err
+	}
+	app.Handle(http.MethodGet, "", "/test", handler)
+
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listening : %s", err)
+	}
+
+	srv := http.Server{
+		Handler:   app,
+		TLSConfig: tlsCfg,
+	}
+	go srv.ServeTLS(ln, "", "")
+	defer srv.Close()
+
+	pool := x509.NewCertPool()
+	pool.AddCert(ca.cert)
+
+	clientCertPEM, clientKeyPEM := ca.issue(t, "client", x509.ExtKeyUsageClientAuth)
+	clientCert, err := tls.X509KeyPair(clientCertPEM, clientKeyPEM)
+	if err != nil {
+		t.Fatalf("loading client certificate : %s", err)
+	}
+
+	t.Log("Given the need to identify clients by their certificate.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen a client connects over mutual TLS.", testID)
+		{
+			client := http.Client{
+				Transport: &http.Transport{
+					TLSClientConfig: &tls.Config{
+						RootCAs:      pool,
+						Certificates: []tls.Certificate{clientCert},
+					},
+				},
+			}
+
+			resp, err := client.Get("https://" + ln.Addr().String() + "/test")
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to make the request : %s", failed, testID, err)
+			}
+			defer resp.Body.Close()
+			t.Logf("\t%s\tTest %d:\tShould be able to make the request.", success, testID)
+
+			body, err := io.ReadAll(resp.Body)
+			if err != nil {
+				t.Fatalf("\t%s\tTest %d:\tShould be able to read the response : %s", failed, testID, err)
+			}
+
+			if subject := string(body); subject != "CN=client" {
+				t.Fatalf("\t%s\tTest %d:\tShould see the client subject : got %q", failed, testID, subject)
+			}
+			t.Logf("\t%s\tTest %d:\tShould see the client subject.", success, testID)
+		}
+	}
+}
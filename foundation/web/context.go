@@ -0,0 +1,99 @@
+package web
+
+import (
+	"context"
+	"time"
+)
+
+// ctxKey represents the type of value for the context key.
+type ctxKey int
+
+// key is how request values are stored/retrieved.
+const key ctxKey = 1
+
+// TraceIDHeader is the response header the trace id for a request is
+// reported under.
+const TraceIDHeader = "X-Trace-ID"
+
+// Values represent state for each request.
+type Values struct {
+	TraceID    string
+	Now        time.Time
+	StatusCode int
+
+	// ClientSubject is the subject of the verified client certificate
+	// when the request was made over mutual TLS.
+	ClientSubject string
+
+	// APIKey is the api key of the request once middleware has
+	// authenticated it.
+	APIKey string
+
+	// accept is the Accept header of the request, used by Respond to
+	// negotiate the media type of the response.
+	accept string
+
+	// method and ifNoneMatch are used by Respond to answer conditional
+	// requests for a representation the client already has.
+	method      string
+	ifNoneMatch string
+}
+
+// GetValues returns the values from the context.
+func GetValues(ctx context.Context) (*Values, error) {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return nil, NewShutdownError("web value missing from context")
+	}
+	return v, nil
+}
+
+// GetTraceID returns the trace id from the context.
+func GetTraceID(ctx context.Context) string {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return "00000000-0000-0000-0000-000000000000"
+	}
+	return v.TraceID
+}
+
+// GetClientSubject returns the subject of the verified client certificate
+// from the context, or an empty string when there is none.
+func GetClientSubject(ctx context.Context) string {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return ""
+	}
+	return v.ClientSubject
+}
+
+// GetAPIKey returns the authenticated api key from the context, or an empty
+// string when there is none.
+func GetAPIKey(ctx context.Context) string {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return ""
+	}
+	return v.APIKey
+}
+
+// SetAPIKey sets the api key of the request into the context. It must only
+// be called once the key has been authenticated.
+func SetAPIKey(ctx context.Context, apiKey string) error {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return NewShutdownError("web value missing from context")
+	}
+	v.APIKey = apiKey
+	return nil
+}
+
+// SetStatusCode sets the status code back into the context.
+func SetStatusCode(ctx context.Context, statusCode int) error {
+	v, ok := ctx.Value(key).(*Values)
+	if !ok {
+		return NewShutdownError("web value missing from context")
+	}
+	v.StatusCode = statusCode
+	return nil
+}
@@ -0,0 +1,94 @@
+package web
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strings"
+)
+
+// ErrPreconditionFailed is returned when the If-Match header of a request
+// doesn't match the current representation of the resource.
+var ErrPreconditionFailed = errors.New("resource has been modified")
+
+// contentCodings are the content codings an entity tag can be marked with
+// when the response body is compressed.
+var contentCodings = []string{"gzip", "br"}
+
+// ETag returns a strong entity tag for the specified response body.
+func ETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// EncodedETag marks a strong entity tag with the content coding negotiated
+// for the response. A compressed body is a different representation, so
+// it can't share the strong entity tag of the uncompressed one. Tags from
+// clients are compared with the mark removed, since both representations
+// describe the same state of the resource.
+func EncodedETag(etag string, coding string) string {
+	if len(etag) < 2 || !strings.HasPrefix(etag, `"`) || !strings.HasSuffix(etag, `"`) {
+		return etag
+	}
+	return strings.TrimSuffix(etag, `"`) + "-" + coding + `"`
+}
+
+// CheckIfMatch enforces the If-Match header of an update against the
+// current state of the resource. The current value must be the same value
+// a GET of the resource responds with, encoded into the same media type
+// through the Accept header of the request, since that is what the client
+// received the entity tag for. Requests without the header are allowed
+// through.
+func CheckIfMatch(r *http.Request, current any) error {
+	ifMatch := r.Header.Get("If-Match")
+	if ifMatch == "" {
+		return nil
+	}
+
+	_, body, err := encode(r.Header.Get("Accept"), current)
+	if err != nil {
+		return err
+	}
+
+	if !matchIfMatch(ifMatch, ETag(body)) {
+		return ErrPreconditionFailed
+	}
+
+	return nil
+}
+
+// matchIfMatch reports if the If-Match header value matches the entity tag
+// using the strong comparison function.
+func matchIfMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		if tag == "*" || trimCoding(tag) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// matchNoneMatch reports if the If-None-Match header value matches the
+// entity tag using the weak comparison function.
+func matchNoneMatch(header string, etag string) bool {
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimPrefix(strings.TrimSpace(tag), "W/")
+		if tag == "*" || trimCoding(tag) == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// trimCoding removes the content coding mark added by EncodedETag.
+func trimCoding(tag string) string {
+	for _, coding := range contentCodings {
+		suffix := "-" + coding + `"`
+		if strings.HasSuffix(tag, suffix) {
+			return strings.TrimSuffix(tag, suffix) + `"`
+		}
+	}
+	return tag
+}
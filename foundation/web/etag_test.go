@@ -0,0 +1,123 @@
+package web_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+// product is a resource served and updated by the tests.
+type product struct {
+	Name string `json:"name" xml:"name"`
+	Cost int    `json:"cost" xml:"cost"`
+}
+
+// get responds with the product the way a GET of the resource would.
+func get(accept string, prd product) *httptest.ResponseRecorder {
+	app := web.NewApp(make(chan os.Signal, 1), zap.NewNop().Sugar())
+
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, prd, http.StatusOK)
+	}
+	app.Handle(http.MethodGet, "", "/product", handler)
+
+	r := httptest.NewRequest(http.MethodGet, "/product", nil)
+	r.Header.Set("Accept", accept)
+	w := httptest.NewRecorder()
+	app.ServeHTTP(w, r)
+
+	return w
+}
+
+func TestCheckIfMatch(t *testing.T) {
+	prd := product{Name: "comic books", Cost: 50}
+	changed := product{Name: "comic books", Cost: 75}
+
+	tt := []struct {
+		name    string
+		accept  string
+		coding  string
+		current product
+		err     error
+	}{
+		{"json unchanged", "application/json", "", prd, nil},
+		{"xml unchanged", "application/xml", "", prd, nil},
+		{"compressed unchanged", "application/json", "gzip", prd, nil},
+		{"json changed", "application/json", "", changed, web.ErrPreconditionFailed},
+		{"xml changed", "application/xml", "", changed, web.ErrPreconditionFailed},
+	}
+
+	t.Log("Given the need to reject updates made against stale representations.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen the resource is %s.", testID, test.name)
+			{
+				etag := get(test.accept, prd).Header().Get("ETag")
+				if etag == "" {
+					t.Fatalf("\t%s\tTest %d:\tShould receive an entity tag.", failed, testID)
+				}
+				if test.coding != "" {
+					etag = web.EncodedETag(etag, test.coding)
+				}
+
+				r := httptest.NewRequest(http.MethodPut, "/product", nil)
+				r.Header.Set("Accept", test.accept)
+				r.Header.Set("If-Match", etag)
+
+				err := web.CheckIfMatch(r, test.current)
+				if !errors.Is(err, test.err) {
+					t.Fatalf("\t%s\tTest %d:\tShould report %v : got %v", failed, testID, test.err, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould report %v.", success, testID, test.err)
+			}
+		}
+	}
+}
+
+func TestIfNoneMatch(t *testing.T) {
+	prd := product{Name: "comic books", Cost: 50}
+
+	t.Log("Given the need to let clients revalidate what they already have.")
+	{
+		etag := get("application/json", prd).Header().Get("ETag")
+
+		tt := []struct {
+			name   string
+			tag    string
+			status int
+		}{
+			{"current", etag, http.StatusNotModified},
+			{"weak", "W/" + etag, http.StatusNotModified},
+			{"compressed", web.EncodedETag(etag, "br"), http.StatusNotModified},
+			{"stale", `"stale"`, http.StatusOK},
+		}
+
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen the client has the %s entity tag.", testID, test.name)
+			{
+				app := web.NewApp(make(chan os.Signal, 1), zap.NewNop().Sugar())
+
+				handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return web.Respond(ctx, w, prd, http.StatusOK)
+				}
+				app.Handle(http.MethodGet, "", "/product", handler)
+
+				r := httptest.NewRequest(http.MethodGet, "/product", nil)
+				r.Header.Set("If-None-Match", test.tag)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != test.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a %d status code : got %d", failed, testID, test.status, w.Code)
+				}
+				t.Logf("\t%s\tTest %d:\tShould receive a %d status code.", success, testID, test.status)
+			}
+		}
+	}
+}
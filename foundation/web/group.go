@@ -0,0 +1,68 @@
+package web
+
+// Route describes a handler bound to a method and path within a group.
+type Route struct {
+	Method  string
+	Path    string
+	Handler Handler
+	Mw      []Middleware
+	Doc     Doc
+}
+
+// Doc describes the route for api consumers. Request and Response hold a
+// value of the type decoded from the request body and written as the
+// response body. They are left nil when there is no body.
+type Doc struct {
+	Summary  string
+	Request  any
+	Response any
+
+	// Status is the status code of a successful response. It defaults
+	// to 200 when not set.
+	Status int
+}
+
+// Endpoint is a route as it was registered with the app.
+type Endpoint struct {
+	Method string
+	Path   string
+	Doc    Doc
+}
+
+// Routes is an ordered set of routes that can be registered as a group.
+type Routes []Route
+
+// Override returns a new set of routes where each provided route replaces
+// the existing route with the same method and path. Routes that don't
+// replace anything are appended. This allows a newer version of an api to
+// inherit the routes of an older one and only redefine what changed.
+func (rs Routes) Override(routes ...Route) Routes {
+	out := make(Routes, len(rs), len(rs)+len(routes))
+	copy(out, rs)
+
+next:
+	for _, route := range routes {
+		for i := range out {
+			if out[i].Method == route.Method && out[i].Path == route.Path {
+				out[i] = route
+				continue next
+			}
+		}
+		out = append(out, route)
+	}
+
+	return out
+}
+
+// HandleGroup registers the set of routes under the specified group. The
+// group middleware runs inside of the application wide middleware and
+// outside of any route specific middleware.
+func (a *App) HandleGroup(group string, routes Routes, mw ...Middleware) {
+	for _, route := range routes {
+		routeMw := make([]Middleware, 0, len(mw)+len(route.Mw))
+		routeMw = append(routeMw, mw...)
+		routeMw = append(routeMw, route.Mw...)
+
+		a.handle(route.Method, group, route.Path, route.Handler, route.Doc, routeMw)
+	}
+}
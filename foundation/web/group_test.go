@@ -0,0 +1,81 @@
+package web_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+// respond returns a handler that responds with the specified value.
+func respond(data string) web.Handler {
+	h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return web.Respond(ctx, w, data, http.StatusOK)
+	}
+
+	return h
+}
+
+func TestOverride(t *testing.T) {
+	v1 := web.Routes{
+		{Method: http.MethodGet, Path: "/users", Handler: respond("v1 users")},
+		{Method: http.MethodPost, Path: "/users", Handler: respond("v1 create")},
+		{Method: http.MethodGet, Path: "/products", Handler: respond("v1 products")},
+	}
+
+	v2 := v1.Override(
+		web.Route{Method: http.MethodGet, Path: "/users", Handler: respond("v2 users")},
+		web.Route{Method: http.MethodGet, Path: "/orders", Handler: respond("v2 orders")},
+	)
+
+	app := web.NewApp(make(chan os.Signal, 1), zap.NewNop().Sugar())
+	app.HandleGroup("v1", v1)
+	app.HandleGroup("v2", v2)
+
+	tt := []struct {
+		method string
+		path   string
+		status int
+		body   string
+	}{
+		{http.MethodGet, "/v1/users", http.StatusOK, `"v1 users"`},
+		{http.MethodGet, "/v2/users", http.StatusOK, `"v2 users"`},
+		{http.MethodPost, "/v2/users", http.StatusOK, `"v1 create"`},
+		{http.MethodGet, "/v2/products", http.StatusOK, `"v1 products"`},
+		{http.MethodGet, "/v2/orders", http.StatusOK, `"v2 orders"`},
+		{http.MethodGet, "/v1/orders", http.StatusNotFound, ""},
+	}
+
+	t.Log("Given the need for a version to inherit and override the routes of another.")
+	{
+		t.Logf("\tTest 0:\tWhen comparing the route sets.")
+		{
+			if len(v1) != 3 || len(v2) != 4 {
+				t.Fatalf("\t%s\tTest 0:\tShould leave the original routes alone : got %d and %d routes", failed, len(v1), len(v2))
+			}
+			t.Logf("\t%s\tTest 0:\tShould leave the original routes alone.", success)
+		}
+
+		for i, test := range tt {
+			testID := i + 1
+			t.Logf("\tTest %d:\tWhen calling %s %s.", testID, test.method, test.path)
+			{
+				r := httptest.NewRequest(test.method, test.path, nil)
+				w := httptest.NewRecorder()
+				app.ServeHTTP(w, r)
+
+				if w.Code != test.status {
+					t.Fatalf("\t%s\tTest %d:\tShould receive a %d status code : got %d", failed, testID, test.status, w.Code)
+				}
+				if test.body != "" && w.Body.String() != test.body {
+					t.Fatalf("\t%s\tTest %d:\tShould be served by %s : got %s", failed, testID, test.body, w.Body.String())
+				}
+				t.Logf("\t%s\tTest %d:\tShould be served as expected.", success, testID)
+			}
+		}
+	}
+}
@@ -0,0 +1,105 @@
+package web
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/http"
+	"time"
+)
+
+// Set of errors reported when a request goes beyond the limits of its route.
+var (
+	ErrBodyTooLarge     = errors.New("request body too large")
+	ErrDeadlineExceeded = errors.New("request deadline exceeded")
+)
+
+// Timeout returns a route option that gives the handler a deadline. The
+// deadline is carried by the context so downstream calls are cancelled
+// with it. Errors returned after the deadline has passed are reported as
+// ErrDeadlineExceeded. The deadline is cooperative: a handler that doesn't
+// watch the context runs to completion and responds as usual, however long
+// it takes.
+func Timeout(d time.Duration) Middleware {
+	m := func(handler Handler) Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			ctx, cancel := context.WithTimeout(ctx, d)
+			defer cancel()
+
+			err := handler(ctx, w, r.WithContext(ctx))
+			if err != nil && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+				return &limitError{limit: ErrDeadlineExceeded, err: err}
+			}
+
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// MaxBodySize returns a route option that limits the request body to the
+// specified number of bytes. Reading past the limit fails with an error
+// that is reported as ErrBodyTooLarge.
+func MaxBodySize(n int64) Middleware {
+	m := func(handler Handler) Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			r.Body = &maxBodyReader{
+				ReadCloser: http.MaxBytesReader(w, r.Body, n),
+				limit:      n,
+			}
+
+			return handler(ctx, w, r)
+		}
+
+		return h
+	}
+
+	return m
+}
+
+// =============================================================================
+
+// limitError reports that a request went beyond one of its limits while
+// keeping the error that was produced as a result.
+type limitError struct {
+	limit error
+	err   error
+}
+
+// Error implements the error interface.
+func (le *limitError) Error() string {
+	return le.limit.Error() + ": " + le.err.Error()
+}
+
+// Is reports the limit that was reached.
+func (le *limitError) Is(target error) bool {
+	return target == le.limit
+}
+
+// Unwrap returns the error produced by reaching the limit.
+func (le *limitError) Unwrap() error {
+	return le.err
+}
+
+// maxBodyReader tracks what has been read through http.MaxBytesReader so
+// its error can be identified once the limit is reached.
+type maxBodyReader struct {
+	io.ReadCloser
+	limit int64
+	read  int64
+}
+
+// Read implements the io.Reader interface.
+func (mr *maxBodyReader) Read(p []byte) (int, error) {
+	n, err := mr.ReadCloser.Read(p)
+	mr.read += int64(n)
+
+	if err != nil && err != io.EOF && mr.read >= mr.limit {
+		return n, &limitError{limit: ErrBodyTooLarge, err: err}
+	}
+
+	return n, err
+}
@@ -0,0 +1,116 @@
+package web_test
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+// trace returns middleware that records when it is entered and left.
+func trace(calls *[]string, name string) web.Middleware {
+	m := func(handler web.Handler) web.Handler {
+		h := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+			*calls = append(*calls, name+" in")
+			err := handler(ctx, w, r)
+			*calls = append(*calls, name+" out")
+			return err
+		}
+
+		return h
+	}
+
+	return m
+}
+
+func TestMiddlewareOrder(t *testing.T) {
+	tt := []struct {
+		name     string
+		register func(app *web.App, calls *[]string, handler web.Handler)
+		path     string
+		want     []string
+	}{
+		{
+			name: "app and route",
+			register: func(app *web.App, calls *[]string, handler web.Handler) {
+				app.Handle(http.MethodGet, "", "/test", handler, trace(calls, "route1"), trace(calls, "route2"))
+			},
+			path: "/test",
+			want: []string{
+				"app1 in", "app2 in", "route1 in", "route2 in",
+				"handler",
+				"route2 out", "route1 out", "app2 out", "app1 out",
+			},
+		},
+		{
+			name: "app, group and route",
+			register: func(app *web.App, calls *[]string, handler web.Handler) {
+				routes := web.Routes{
+					{Method: http.MethodGet, Path: "/test", Handler: handler, Mw: []web.Middleware{trace(calls, "route")}},
+				}
+				app.HandleGroup("v1", routes, trace(calls, "group1"), trace(calls, "group2"))
+			},
+			path: "/v1/test",
+			want: []string{
+				"app1 in", "app2 in", "group1 in", "group2 in", "route in",
+				"handler",
+				"route out", "group2 out", "group1 out", "app2 out", "app1 out",
+			},
+		},
+		{
+			name: "app after NewApp",
+			register: func(app *web.App, calls *[]string, handler web.Handler) {
+				app.EnableCORS(trace(calls, "app3"))
+				app.Handle(http.MethodGet, "", "/test", handler)
+			},
+			path: "/test",
+			want: []string{
+				"app1 in", "app2 in", "app3 in",
+				"handler",
+				"app3 out", "app2 out", "app1 out",
+			},
+		},
+		{
+			name: "nil middleware",
+			register: func(app *web.App, calls *[]string, handler web.Handler) {
+				app.Handle(http.MethodGet, "", "/test", handler, nil, trace(calls, "route"))
+			},
+			path: "/test",
+			want: []string{
+				"app1 in", "app2 in", "route in",
+				"handler",
+				"route out", "app2 out", "app1 out",
+			},
+		},
+	}
+
+	t.Log("Given the need to run middleware in a deterministic order.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen registering %s middleware.", testID, test.name)
+			{
+				var calls []string
+				app := web.NewApp(make(chan os.Signal, 1), zap.NewNop().Sugar(), trace(&calls, "app1"), trace(&calls, "app2"))
+
+				handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					calls = append(calls, "handler")
+					return nil
+				}
+				test.register(app, &calls, handler)
+
+				r := httptest.NewRequest(http.MethodGet, test.path, nil)
+				app.ServeHTTP(httptest.NewRecorder(), r)
+
+				if !reflect.DeepEqual(calls, test.want) {
+					t.Fatalf("\t%s\tTest %d:\tShould run in order %v : got %v", failed, testID, test.want, calls)
+				}
+				t.Logf("\t%s\tTest %d:\tShould run in order.", success, testID)
+			}
+		}
+	}
+}
@@ -0,0 +1,76 @@
+package web
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/dimfeld/httptreemux/v5"
+)
+
+// ErrMalformedBody is reported when the request body can't be decoded into
+// the value provided to Decode.
+var ErrMalformedBody = errors.New("malformed request body")
+
+// validator is implemented by values that know how to validate themselves
+// once they have been decoded.
+type validator interface {
+	Validate() error
+}
+
+// Param returns the web call parameters from the request.
+func Param(r *http.Request, key string) string {
+	m := httptreemux.ContextParams(r.Context())
+	return m[key]
+}
+
+// Decode reads the body of an HTTP request looking for a JSON document. The
+// body is decoded into the provided value. Fields in the document that are
+// not declared by the value are rejected. A body that can't be decoded is
+// reported as ErrMalformedBody, unless it was too large.
+//
+// If the provided value implements the validator interface, its Validate
+// method is called once decoding succeeds and any error it reports is
+// returned as is. This is how struct tag validation is applied, since the
+// validation rules belong to the business layer.
+func Decode(r *http.Request, val any) error {
+	decoder := json.NewDecoder(r.Body)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(val); err != nil {
+		if errors.Is(err, ErrBodyTooLarge) {
+			return err
+		}
+		return &decodeError{err: err}
+	}
+
+	if v, ok := val.(validator); ok {
+		if err := v.Validate(); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// =============================================================================
+
+// decodeError reports a request body that couldn't be decoded while keeping
+// the reason it failed.
+type decodeError struct {
+	err error
+}
+
+// Error implements the error interface.
+func (de *decodeError) Error() string {
+	return ErrMalformedBody.Error() + ": " + de.err.Error()
+}
+
+// Is reports the error as ErrMalformedBody.
+func (de *decodeError) Is(target error) bool {
+	return target == ErrMalformedBody
+}
+
+// Unwrap returns the error produced by the decoder.
+func (de *decodeError) Unwrap() error {
+	return de.err
+}
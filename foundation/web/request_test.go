@@ -0,0 +1,86 @@
+package web_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/Joggz/services/foundation/web"
+)
+
+// user is a value decoded from test requests.
+type user struct {
+	Name string `json:"name"`
+}
+
+// Validate rejects users without a name.
+func (u user) Validate() error {
+	if u.Name == "" {
+		return errNameRequired
+	}
+	return nil
+}
+
+var errNameRequired = errors.New("name is required")
+
+func TestDecode(t *testing.T) {
+	tt := []struct {
+		name string
+		body string
+		err  error
+	}{
+		{"valid", `{"name":"bill"}`, nil},
+		{"malformed", `{"name":`, web.ErrMalformedBody},
+		{"unknown field", `{"name":"bill","age":42}`, web.ErrMalformedBody},
+		{"empty", ``, web.ErrMalformedBody},
+		{"invalid", `{"name":""}`, errNameRequired},
+	}
+
+	t.Log("Given the need to decode and validate request bodies.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen decoding a %s body.", testID, test.name)
+			{
+				r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(test.body))
+
+				var u user
+				err := web.Decode(r, &u)
+
+				if test.err == nil && err != nil {
+					t.Fatalf("\t%s\tTest %d:\tShould be able to decode the body : %s", failed, testID, err)
+				}
+				if test.err != nil && !errors.Is(err, test.err) {
+					t.Fatalf("\t%s\tTest %d:\tShould report %q : got %v", failed, testID, test.err, err)
+				}
+				t.Logf("\t%s\tTest %d:\tShould report %v.", success, testID, test.err)
+			}
+		}
+	}
+}
+
+func TestDecodeTooLarge(t *testing.T) {
+	t.Log("Given the need to limit the size of request bodies.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen decoding a body over the limit.", testID)
+		{
+			var err error
+			handler := web.MaxBodySize(8)(func(_ context.Context, w http.ResponseWriter, r *http.Request) error {
+				var u user
+				err = web.Decode(r, &u)
+				return nil
+			})
+
+			r := httptest.NewRequest(http.MethodPost, "/", strings.NewReader(`{"name":"william"}`))
+			handler(context.Background(), httptest.NewRecorder(), r)
+
+			if !errors.Is(err, web.ErrBodyTooLarge) || errors.Is(err, web.ErrMalformedBody) {
+				t.Fatalf("\t%s\tTest %d:\tShould report the body too large : got %v", failed, testID, err)
+			}
+			t.Logf("\t%s\tTest %d:\tShould report the body too large.", success, testID)
+		}
+	}
+}
@@ -0,0 +1,165 @@
+package web
+
+import (
+	"context"
+	"encoding/json"
+	"encoding/xml"
+	"net/http"
+	"strconv"
+	"strings"
+)
+
+// Set of media types the framework can encode responses into.
+const (
+	MediaTypeJSON = "application/json"
+	MediaTypeXML  = "application/xml"
+)
+
+// encoders maps a supported media type to the function that marshals
+// a response value into it. The first entry in offers is the default.
+var encoders = map[string]func(v any) ([]byte, error){
+	MediaTypeJSON: json.Marshal,
+	MediaTypeXML:  xml.Marshal,
+}
+
+// offers is the ordered list of media types used during negotiation.
+var offers = []string{MediaTypeJSON, MediaTypeXML}
+
+// Respond converts a Go value to the media type requested by the client
+// through the Accept header and sends it to the client. JSON is used when
+// the client does not express a preference we can satisfy.
+func Respond(ctx context.Context, w http.ResponseWriter, data any, statusCode int) error {
+
+	// Set the status code for the request logger middleware.
+	if err := SetStatusCode(ctx, statusCode); err != nil {
+		return err
+	}
+
+	// If there is nothing to marshal then set status code and return.
+	if statusCode == http.StatusNoContent {
+		w.WriteHeader(statusCode)
+		return nil
+	}
+
+	// Convert the response value to the media type requested. The values
+	// can't be missing since SetStatusCode succeeded.
+	v, _ := GetValues(ctx)
+	mediaType, data2, err := encode(v.accept, data)
+	if err != nil {
+		return err
+	}
+
+	// Set the content type and headers once we know marshaling has succeeded.
+	w.Header().Set("Content-Type", mediaType)
+	w.Header().Add("Vary", "Accept")
+
+	// Successful responses carry an entity tag so clients can revalidate
+	// what they have instead of downloading it again.
+	if statusCode == http.StatusOK {
+		etag := ETag(data2)
+		w.Header().Set("ETag", etag)
+
+		if (v.method == http.MethodGet || v.method == http.MethodHead) && matchNoneMatch(v.ifNoneMatch, etag) {
+			if err := SetStatusCode(ctx, http.StatusNotModified); err != nil {
+				return err
+			}
+
+			// The Content-Type is left for middleware that works off it,
+			// net/http removes it before the 304 is sent.
+			w.WriteHeader(http.StatusNotModified)
+			return nil
+		}
+	}
+
+	// Write the status code to the response.
+	w.WriteHeader(statusCode)
+
+	// Send the result back to the client.
+	if _, err := w.Write(data2); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+// encode converts the value to the supported media type that best matches
+// the specified Accept header value. Not every value can be expressed in
+// every encoding, so it falls back to JSON rather than failing a request
+// that was otherwise successful.
+func encode(accept string, data any) (string, []byte, error) {
+	mediaType := negotiate(accept)
+
+	body, err := encoders[mediaType](data)
+	if err != nil && mediaType != MediaTypeJSON {
+		mediaType = MediaTypeJSON
+		body, err = encoders[mediaType](data)
+	}
+	if err != nil {
+		return "", nil, err
+	}
+
+	return mediaType, body, nil
+}
+
+// negotiate returns the supported media type that best matches the
+// specified Accept header value.
+func negotiate(accept string) string {
+	if accept == "" {
+		return offers[0]
+	}
+
+	best := offers[0]
+	bestQ := -1.0
+
+	for _, part := range strings.Split(accept, ",") {
+		mediaRange, q := parseMediaRange(part)
+		if q <= 0 || q <= bestQ {
+			continue
+		}
+
+		for _, offer := range offers {
+			if matchMediaRange(mediaRange, offer) {
+				best = offer
+				bestQ = q
+				break
+			}
+		}
+	}
+
+	return best
+}
+
+// parseMediaRange splits an Accept header element into its media range
+// and quality value.
+func parseMediaRange(part string) (string, float64) {
+	params := strings.Split(part, ";")
+	mediaRange := strings.ToLower(strings.TrimSpace(params[0]))
+
+	q := 1.0
+	for _, param := range params[1:] {
+		name, value, ok := strings.Cut(strings.TrimSpace(param), "=")
+		if !ok || strings.ToLower(strings.TrimSpace(name)) != "q" {
+			continue
+		}
+
+		f, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+		if err != nil {
+			return mediaRange, 0
+		}
+		q = f
+	}
+
+	return mediaRange, q
+}
+
+// matchMediaRange reports if the media range accepts the media type.
+func matchMediaRange(mediaRange string, mediaType string) bool {
+	switch {
+	case mediaRange == "*/*":
+		return true
+	case strings.HasSuffix(mediaRange, "/*"):
+		return strings.HasPrefix(mediaType, strings.TrimSuffix(mediaRange, "*"))
+	default:
+		return mediaRange == mediaType
+	}
+}
@@ -0,0 +1,151 @@
+// Package web contains a small web framework extension.
+package web
+
+import (
+	"context"
+	"net/http"
+	"os"
+	"syscall"
+	"time"
+
+	"github.com/dimfeld/httptreemux/v5"
+	"github.com/google/uuid"
+	"go.uber.org/zap"
+)
+
+// A Handler is a type that handles a http request within our own little mini
+// framework.
+type Handler func(ctx context.Context, w http.ResponseWriter, r *http.Request) error
+
+// App is the entrypoint into our application and what configures our context
+// object for each of our http handlers. Feel free to add any configuration
+// data/logic on this App struct.
+type App struct {
+	*httptreemux.ContextMux
+	shutdown  chan os.Signal
+	log       *zap.SugaredLogger
+	mw        []Middleware
+	endpoints []Endpoint
+}
+
+// NewApp creates an App value that handle a set of routes for the application.
+// The provided middleware is applied to every route, with the first middleware
+// in the list being the outermost.
+func NewApp(shutdown chan os.Signal, log *zap.SugaredLogger, mw ...Middleware) *App {
+	return &App{
+		ContextMux: httptreemux.NewContextMux(),
+		shutdown:   shutdown,
+		log:        log,
+		mw:         mw,
+	}
+}
+
+// SignalShutdown is used to gracefully shut down the app when an integrity
+// issue is identified. It never blocks, since a shutdown that is already
+// underway may no longer be reading the channel.
+func (a *App) SignalShutdown() {
+	select {
+	case a.shutdown <- syscall.SIGTERM:
+	default:
+	}
+}
+
+// Handle sets a handler function for a given HTTP method and path pair
+// to the application server mux. Route specific middleware runs inside of
+// the application wide middleware.
+func (a *App) Handle(method string, group string, path string, handler Handler, mw ...Middleware) {
+	a.handle(method, group, path, handler, Doc{}, mw)
+}
+
+// Endpoints returns the set of endpoints registered with the app in the
+// order they were registered.
+func (a *App) Endpoints() []Endpoint {
+	endpoints := make([]Endpoint, len(a.endpoints))
+	copy(endpoints, a.endpoints)
+	return endpoints
+}
+
+// EnableCORS adds the CORS middleware to the application wide middleware and
+// answers OPTIONS requests for every route that doesn't define its own, so
+// preflight requests reach the middleware instead of failing with a 405. It
+// must be called before any routes are registered.
+func (a *App) EnableCORS(mw Middleware) {
+	a.mw = append(a.mw, mw)
+
+	// Anything that makes it past the middleware is an OPTIONS request the
+	// middleware didn't answer, so there is nothing more to say.
+	handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+		return Respond(ctx, w, nil, http.StatusNoContent)
+	}
+	h := a.serve(wrapMiddleware(a.mw, handler))
+
+	a.ContextMux.OptionsHandler = func(w http.ResponseWriter, r *http.Request, _ map[string]string) {
+		h(w, r)
+	}
+}
+
+// handle performs the registration of the handler and records the
+// endpoint with its documentation.
+func (a *App) handle(method string, group string, path string, handler Handler, doc Doc, mw []Middleware) {
+
+	// First wrap handler specific middleware around this handler.
+	handler = wrapMiddleware(mw, handler)
+
+	// Add the application's general middleware to the handler chain.
+	handler = wrapMiddleware(a.mw, handler)
+
+	// The function to execute for each request.
+	h := a.serve(handler)
+
+	finalPath := path
+	if group != "" {
+		finalPath = "/" + group + path
+	}
+
+	a.ContextMux.Handle(method, finalPath, h)
+
+	a.endpoints = append(a.endpoints, Endpoint{
+		Method: method,
+		Path:   finalPath,
+		Doc:    doc,
+	})
+}
+
+// serve returns the function the mux executes for each request, which
+// prepares the request context and calls the fully wrapped handler.
+func (a *App) serve(handler Handler) http.HandlerFunc {
+	h := func(w http.ResponseWriter, r *http.Request) {
+
+		// Set the context with the required values to
+		// process the request.
+		v := Values{
+			TraceID:     uuid.New().String(),
+			Now:         time.Now().UTC(),
+			accept:      r.Header.Get("Accept"),
+			method:      r.Method,
+			ifNoneMatch: r.Header.Get("If-None-Match"),
+		}
+		if r.TLS != nil && len(r.TLS.VerifiedChains) > 0 && len(r.TLS.VerifiedChains[0]) > 0 {
+			v.ClientSubject = r.TLS.VerifiedChains[0][0].Subject.String()
+		}
+		ctx := context.WithValue(r.Context(), key, &v)
+
+		// Echo the trace id back so clients can correlate their requests
+		// with our logs.
+		w.Header().Set(TraceIDHeader, v.TraceID)
+
+		// Call the wrapped handler functions. Only shutdown errors are
+		// expected to make it this far, anything else was missed by the
+		// middleware and is logged rather than taking the service down.
+		if err := handler(ctx, w, r); err != nil {
+			if IsShutdown(err) {
+				a.SignalShutdown()
+				return
+			}
+
+			a.log.Errorw("unhandled error", "traceid", v.TraceID, "method", r.Method, "path", r.URL.Path, "ERROR", err)
+		}
+	}
+
+	return h
+}
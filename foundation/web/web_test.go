@@ -0,0 +1,89 @@
+package web_test
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/Joggz/services/foundation/web"
+	"go.uber.org/zap"
+)
+
+// Success and failure markers.
+const (
+	success = "\u2713"
+	failed  = "\u2717"
+)
+
+func TestShutdown(t *testing.T) {
+	tt := []struct {
+		name     string
+		err      error
+		shutdown bool
+	}{
+		{"nil", nil, false},
+		{"error", errors.New("write: broken pipe"), false},
+		{"shutdown", web.NewShutdownError("integrity issue"), true},
+	}
+
+	t.Log("Given the need to only shut down the service for shutdown errors.")
+	{
+		for testID, test := range tt {
+			t.Logf("\tTest %d:\tWhen a handler returns a %s error.", testID, test.name)
+			{
+				shutdown := make(chan os.Signal, 1)
+				app := web.NewApp(shutdown, zap.NewNop().Sugar())
+
+				handler := func(ctx context.Context, w http.ResponseWriter, r *http.Request) error {
+					return test.err
+				}
+				app.Handle(http.MethodGet, "", "/test", handler)
+
+				r := httptest.NewRequest(http.MethodGet, "/test", nil)
+				app.ServeHTTP(httptest.NewRecorder(), r)
+
+				var got bool
+				select {
+				case <-shutdown:
+					got = true
+				default:
+				}
+
+				if got != test.shutdown {
+					t.Fatalf("\t%s\tTest %d:\tShould signal shutdown %v : got %v", failed, testID, test.shutdown, got)
+				}
+				t.Logf("\t%s\tTest %d:\tShould signal shutdown %v.", success, testID, test.shutdown)
+			}
+		}
+	}
+}
+
+func TestSignalShutdownTwice(t *testing.T) {
+	t.Log("Given the need to signal shutdown without blocking handlers.")
+	{
+		testID := 0
+		t.Logf("\tTest %d:\tWhen nobody is reading the shutdown channel.", testID)
+		{
+			shutdown := make(chan os.Signal, 1)
+			app := web.NewApp(shutdown, zap.NewNop().Sugar())
+
+			done := make(chan struct{})
+			go func() {
+				app.SignalShutdown()
+				app.SignalShutdown()
+				close(done)
+			}()
+
+			select {
+			case <-done:
+			case <-time.After(time.Second):
+				t.Fatalf("\t%s\tTest %d:\tShould not block signaling shutdown.", failed, testID)
+			}
+			t.Logf("\t%s\tTest %d:\tShould not block signaling shutdown.", success, testID)
+		}
+	}
+}